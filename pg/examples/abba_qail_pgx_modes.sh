@@ -15,6 +15,18 @@ WORKLOADS="${WORKLOADS:-point wide_rows large_rows many_params aggregate}"
 MODES="${MODES:-single pipeline pool10 latency}"
 STMT_MODES="${STMT_MODES:-prepared unprepared}"
 RUNNERS="${RUNNERS:-pgx qail_rs qail_zig}"
+# MODES=ingest runs these load strategies instead of WORKLOADS/STMT_MODES.
+INGEST_WORKLOADS="${INGEST_WORKLOADS:-multi_values pipelined copy}"
+
+QUERY_MODES=""
+RUN_INGEST=0
+for mode in ${MODES}; do
+  if [[ "${mode}" == "ingest" ]]; then
+    RUN_INGEST=1
+  else
+    QUERY_MODES="${QUERY_MODES:+${QUERY_MODES} }${mode}"
+  fi
+done
 
 ZIG_REPO_ROOT="${ZIG_REPO_ROOT:-$(cd "${REPO_ROOT}/.." && pwd)/qail-zig}"
 ZIG_BIN="${ZIG_BIN:-/tmp/qail_zig_modes_once}"
@@ -38,6 +50,20 @@ runner_enabled() {
   return 1
 }
 
+# Rotate the runner order each round so no runner always goes first; sets
+# the global order array.
+set_round_order() {
+  local round="$1"
+  shift
+  local runners=("$@")
+  local count="${#runners[@]}"
+  local idx
+  order=()
+  for ((idx = 0; idx < count; idx++)); do
+    order+=("${runners[$(((round + idx) % count))]}")
+  done
+}
+
 calc_median() {
   printf '%s\n' "$@" | LC_ALL=C sort -n | awk '
     {a[NR]=$1}
@@ -114,6 +140,24 @@ run_qail_zig_once() {
   "${ZIG_BIN}" "${mode}" --workload "${workload}" --plain
 }
 
+run_ingest_once() {
+  local runner="$1"
+  local workload="$2"
+  case "${runner}" in
+    pgx) /tmp/pgx_modes_once -mode ingest -workload "${workload}" -plain ;;
+    qail_rs)
+      "${RUST_TARGET_DIR}/release/examples/qail_pgx_modes_once" \
+        ingest \
+        --workload "${workload}" \
+        --plain
+      ;;
+    *)
+      echo "unknown ingest runner: ${runner}" >&2
+      return 1
+      ;;
+  esac
+}
+
 run_once() {
   local runner="$1"
   local mode="$2"
@@ -133,15 +177,16 @@ run_once() {
 print_runner_summary() {
   local label="$1"
   local array_name="$2"
+  local unit="${3:-q/s}"
   local runs=()
   if ! declare -p "${array_name}" >/dev/null 2>&1; then
-    printf "    %-9s median/p95: %8s / %8s q/s\n" "${label}" "n/a" "n/a"
+    printf "    %-9s median/p95: %8s / %8s %s\n" "${label}" "n/a" "n/a" "${unit}"
     return
   fi
   local run_count
   eval "run_count=\${#${array_name}[@]}"
   if [[ "${run_count}" -eq 0 ]]; then
-    printf "    %-9s median/p95: %8s / %8s q/s\n" "${label}" "n/a" "n/a"
+    printf "    %-9s median/p95: %8s / %8s %s\n" "${label}" "n/a" "n/a" "${unit}"
     return
   fi
   eval "runs=(\"\${${array_name}[@]}\")"
@@ -149,7 +194,7 @@ print_runner_summary() {
   local median p95
   median="$(calc_median "${runs[@]}")"
   p95="$(calc_percentile 0.95 "${runs[@]}")"
-  printf "    %-9s median/p95: %8.0f / %8.0f q/s\n" "${label}" "${median}" "${p95}"
+  printf "    %-9s median/p95: %8.0f / %8.0f %s\n" "${label}" "${median}" "${p95}" "${unit}"
 }
 
 print_delta_summary() {
@@ -199,6 +244,9 @@ echo "runners=${RUNNERS}"
 echo "modes=${MODES}"
 echo "statement_modes=${STMT_MODES}"
 echo "workloads=${WORKLOADS}"
+if [[ "${RUN_INGEST}" -eq 1 ]]; then
+  echo "ingest_workloads=${INGEST_WORKLOADS}"
+fi
 if [[ -n "${DATABASE_URL:-${QAIL_BENCH_DATABASE_URL:-}}" ]]; then
   echo "db_target=env-configured"
 else
@@ -210,6 +258,10 @@ echo "Building QAIL Rust runner..."
 (
   cd "${REPO_ROOT}"
   CARGO_TARGET_DIR="${RUST_TARGET_DIR}" cargo build --release -p qail-pg --example qail_native_pgx_once >/dev/null
+  # qail_native_pgx_once has no ingest mode; ingest runs qail_pgx_modes_once.
+  if [[ "${RUN_INGEST}" -eq 1 ]] && runner_enabled qail_rs; then
+    CARGO_TARGET_DIR="${RUST_TARGET_DIR}" cargo build --release -p qail-pg --example qail_pgx_modes_once >/dev/null
+  fi
 )
 
 echo "Building PGX runner..."
@@ -246,7 +298,7 @@ if runner_enabled qail_zig; then
 fi
 
 echo
-for workload in ${WORKLOADS}; do
+for workload in ${QUERY_MODES:+${WORKLOADS}}; do
   case "${workload}" in
     point) workload_label="Workload: point lookup (1 row)" ;;
     wide_rows) workload_label="Workload: wide rows (128-512 rows/query, mixed types)" ;;
@@ -257,7 +309,7 @@ for workload in ${WORKLOADS}; do
   esac
   echo "${workload_label}"
 
-  for mode in ${MODES}; do
+  for mode in ${QUERY_MODES}; do
     case "${mode}" in
       single) label="  Mode 1: multi single-query (1 conn)" ;;
       pipeline) label="  Mode 2: pipelined batch (1 conn)" ;;
@@ -321,28 +373,7 @@ for workload in ${WORKLOADS}; do
       pgx_p99_runs=()
 
       for ((i = 0; i < ROUNDS; i++)); do
-        case "${#active_runners[@]}" in
-          1)
-            order=("${active_runners[0]}")
-            ;;
-          2)
-            case $((i % 2)) in
-              0) order=("${active_runners[0]}" "${active_runners[1]}") ;;
-              1) order=("${active_runners[1]}" "${active_runners[0]}") ;;
-            esac
-            ;;
-          3)
-            case $((i % 3)) in
-              0) order=("${active_runners[0]}" "${active_runners[1]}" "${active_runners[2]}") ;;
-              1) order=("${active_runners[1]}" "${active_runners[2]}" "${active_runners[0]}") ;;
-              2) order=("${active_runners[2]}" "${active_runners[0]}" "${active_runners[1]}") ;;
-            esac
-            ;;
-          *)
-            echo "unsupported runner count: ${#active_runners[@]}" >&2
-            exit 1
-            ;;
-        esac
+        set_round_order "${i}" "${active_runners[@]}"
 
         order_desc="$(printf '%s -> ' "${order[@]}")"
         order_desc="${order_desc% -> }"
@@ -462,3 +493,75 @@ for workload in ${WORKLOADS}; do
     done
   done
 done
+
+if [[ "${RUN_INGEST}" -eq 1 ]]; then
+  active_runners=()
+  for runner in ${RUNNERS}; do
+    case "${runner}" in
+      pgx | qail_rs) active_runners+=("${runner}") ;;
+    esac
+  done
+
+  for workload in ${INGEST_WORKLOADS}; do
+    case "${workload}" in
+      multi_values) label="Ingest: multi-row INSERT ... VALUES (1 conn)" ;;
+      pipelined) label="Ingest: pipelined single-row INSERT (1 conn)" ;;
+      copy) label="Ingest: COPY FROM STDIN (1 conn)" ;;
+      *) label="Ingest: ${workload}" ;;
+    esac
+    echo "${label}"
+
+    if runner_enabled qail_zig; then
+      echo "    note: qail-zig has no ingest mode; skipped"
+    fi
+    if [[ "${#active_runners[@]}" -eq 0 ]]; then
+      echo "    no active runners for this slice"
+      echo
+      continue
+    fi
+
+    pgx_runs=()
+    qail_rs_runs=()
+    for ((i = 0; i < ROUNDS; i++)); do
+      set_round_order "${i}" "${active_runners[@]}"
+      order_desc="$(printf '%s -> ' "${order[@]}")"
+      order_desc="${order_desc% -> }"
+      echo "    Round $((i + 1)) (${order_desc})"
+
+      unset pgx_rows qail_rs_rows
+      for runner in "${order[@]}"; do
+        rows_per_sec="$(run_ingest_once "${runner}" "${workload}")"
+        case "${runner}" in
+          pgx)
+            pgx_rows="${rows_per_sec}"
+            pgx_runs+=("${rows_per_sec}")
+            ;;
+          qail_rs)
+            qail_rs_rows="${rows_per_sec}"
+            qail_rs_runs+=("${rows_per_sec}")
+            ;;
+        esac
+      done
+
+      for runner in "${active_runners[@]}"; do
+        case "${runner}" in
+          pgx) printf "      pgx      : %8.0f rows/s\n" "${pgx_rows}" ;;
+          qail_rs) printf "      qail-rs  : %8.0f rows/s\n" "${qail_rs_rows}" ;;
+        esac
+      done
+    done
+
+    unset pgx_median qail_rs_median
+    if [[ "${#pgx_runs[@]}" -gt 0 ]]; then
+      pgx_median="$(calc_median "${pgx_runs[@]}")"
+    fi
+    if [[ "${#qail_rs_runs[@]}" -gt 0 ]]; then
+      qail_rs_median="$(calc_median "${qail_rs_runs[@]}")"
+    fi
+
+    print_runner_summary "pgx" pgx_runs rows/s
+    print_runner_summary "qail-rs" qail_rs_runs rows/s
+    print_delta_summary "delta (qail-rs vs pgx, median)" "${qail_rs_median:-}" "${pgx_median:-}"
+    echo
+  done
+fi
@@ -7,13 +7,22 @@
 //!   cargo run --release -p qail-pg --example qail_pgx_modes_once -- single --workload wide_rows --plain
 //!   cargo run --release -p qail-pg --example qail_pgx_modes_once -- pipeline --workload many_params --plain
 //!   cargo run --release -p qail-pg --example qail_pgx_modes_once -- latency --workload monster_cte --plain
+//!   cargo run --release -p qail-pg --example qail_pgx_modes_once -- ingest --workload copy --plain
+//!
+//! Fault mode runs a PgPool through the qailbench fault proxy and needs
+//! QAIL_BENCH_FAULT_PROXY and QAIL_BENCH_FAULT_CONTROL; the Go harness starts
+//! the proxy and sets both:
+//!   go run ./pgx_benchmark.go --driver qail --qail-bin <path to this example> --mode fault --fault reset
 
 use qail_pg::driver::PreparedStatement;
 use qail_pg::{
     ConnectOptions, PgBytesRow, PgConnection, PgEncoder, PgPool, PgRow, PoolConfig, TlsMode,
 };
 use std::sync::Arc;
+use std::sync::atomic::{AtomicBool, Ordering};
 use std::time::{Duration, Instant};
+use tokio::io::{AsyncBufReadExt, AsyncWriteExt, BufReader};
+use tokio::net::TcpStream;
 use tokio::sync::Barrier;
 use tokio::task::JoinSet;
 
@@ -104,6 +113,36 @@ const CREATE_BENCH_PAYLOAD_SQL: &str = concat!(
     "optional_note TEXT NULL",
     ")"
 );
+const INGEST_TOTAL_ROWS: usize = 100_000;
+const INGEST_ITERATIONS: usize = 3;
+const INGEST_MULTI_VALUES_ROWS: usize = 500;
+const INGEST_PIPELINE_DEPTH: usize = 10_000;
+/// Bind parameters per statement are capped at 65535 by the protocol.
+const INGEST_MULTI_VALUES_MAX_ROWS: usize = 65_535 / INGEST_COLUMNS.len();
+const INGEST_TABLE: &str = "qail_bench_ingest";
+const INGEST_COLUMNS: [&str; 6] = ["id", "name", "visits", "active", "ratio", "note"];
+const CREATE_BENCH_INGEST_SQL: &str = concat!(
+    "CREATE TABLE IF NOT EXISTS qail_bench_ingest (",
+    "id INTEGER NOT NULL, ",
+    "name TEXT NOT NULL, ",
+    "visits INTEGER NOT NULL, ",
+    "active BOOLEAN NOT NULL, ",
+    "ratio NUMERIC(12, 3) NOT NULL, ",
+    "note TEXT NULL",
+    ")"
+);
+const TRUNCATE_BENCH_INGEST_SQL: &str = "TRUNCATE qail_bench_ingest";
+const COUNT_BENCH_INGEST_SQL: &str = "SELECT COUNT(*) FROM qail_bench_ingest";
+const INGEST_ROW_SQL: &str = concat!(
+    "INSERT INTO qail_bench_ingest (id, name, visits, active, ratio, note) ",
+    "VALUES ($1::int, $2::text, $3::int, $4::bool, $5::numeric, $6::text)"
+);
+// Fault schedule, matching qailbench/workload.go.
+const FAULT_LEAD_TIME: Duration = Duration::from_secs(2);
+const FAULT_DURATION: Duration = Duration::from_secs(3);
+const FAULT_TAIL_TIME: Duration = Duration::from_secs(5);
+const FAULT_QUERY_TIMEOUT: Duration = Duration::from_secs(1);
+const FAULT_ERROR_BACKOFF: Duration = Duration::from_millis(10);
 
 #[derive(Clone, Copy, Debug)]
 enum Mode {
@@ -111,6 +150,8 @@ enum Mode {
     Pipeline,
     Pool10,
     Latency,
+    Ingest,
+    Fault,
 }
 
 impl Mode {
@@ -120,8 +161,10 @@ impl Mode {
             "pipeline" => Ok(Self::Pipeline),
             "pool10" | "pool" => Ok(Self::Pool10),
             "latency" | "lat" => Ok(Self::Latency),
+            "ingest" => Ok(Self::Ingest),
+            "fault" => Ok(Self::Fault),
             other => Err(format!(
-                "unknown mode '{}' (expected single | pipeline | pool10 | latency)",
+                "unknown mode '{}' (expected single | pipeline | pool10 | latency | ingest | fault)",
                 other
             )),
         }
@@ -133,6 +176,30 @@ impl Mode {
             Self::Pipeline => "pipeline",
             Self::Pool10 => "pool10",
             Self::Latency => "latency",
+            Self::Ingest => "ingest",
+            Self::Fault => "fault",
+        }
+    }
+
+    /// Flags this mode reads besides the mode itself and `--format`.
+    fn accepted_flags(self) -> &'static [&'static str] {
+        match self {
+            Self::Single | Self::Pipeline | Self::Pool10 => &[
+                "--workload",
+                "--stmt-mode",
+                "--batch-size",
+                "--iterations",
+                "--plain",
+            ],
+            Self::Latency => &[
+                "--workload",
+                "--stmt-mode",
+                "--batch-size",
+                "--samples",
+                "--plain",
+            ],
+            Self::Ingest => &["--workload", "--batch-size", "--iterations", "--plain"],
+            Self::Fault => &["--workload", "--fault", "--batch-size", "--plain"],
         }
     }
 }
@@ -188,6 +255,95 @@ impl Workload {
     }
 }
 
+#[derive(Clone, Copy, Debug)]
+enum FaultKind {
+    /// Drop every proxied connection and refuse new ones.
+    Reset,
+    /// Keep connections open but stop forwarding bytes.
+    Blackhole,
+}
+
+impl FaultKind {
+    fn parse(s: &str) -> Result<Self, String> {
+        match s {
+            "reset" | "restart" | "kill" => Ok(Self::Reset),
+            "blackhole" | "drop" | "partition" => Ok(Self::Blackhole),
+            other => Err(format!(
+                "unknown fault '{}' (expected reset | blackhole)",
+                other
+            )),
+        }
+    }
+
+    fn name(self) -> &'static str {
+        match self {
+            Self::Reset => "reset",
+            Self::Blackhole => "blackhole",
+        }
+    }
+}
+
+#[derive(Clone, Copy, Debug)]
+enum IngestStrategy {
+    MultiValues,
+    Pipelined,
+    Copy,
+}
+
+impl IngestStrategy {
+    const ALL: [Self; 3] = [Self::MultiValues, Self::Pipelined, Self::Copy];
+
+    fn parse(s: &str) -> Result<Self, String> {
+        match s {
+            "multi_values" | "multi" | "values" => Ok(Self::MultiValues),
+            "pipelined" | "pipeline_rows" | "single_row" => Ok(Self::Pipelined),
+            "copy" | "copy_from" => Ok(Self::Copy),
+            other => Err(format!(
+                "unknown ingest workload '{}' (expected multi_values | pipelined | copy)",
+                other
+            )),
+        }
+    }
+
+    fn name(self) -> &'static str {
+        match self {
+            Self::MultiValues => "multi_values",
+            Self::Pipelined => "pipelined",
+            Self::Copy => "copy",
+        }
+    }
+
+    /// Rows per statement (multi_values), per pipeline sync (pipelined), or per
+    /// COPY (copy); zero selects the strategy default.
+    fn batch_size(self, requested: usize) -> Result<usize, String> {
+        match self {
+            Self::MultiValues if requested > INGEST_MULTI_VALUES_MAX_ROWS => Err(format!(
+                "multi_values batch size {} exceeds {} rows (65535 bind parameters)",
+                requested, INGEST_MULTI_VALUES_MAX_ROWS
+            )),
+            _ if requested > 0 => Ok(requested),
+            Self::MultiValues => Ok(INGEST_MULTI_VALUES_ROWS),
+            Self::Pipelined => Ok(INGEST_PIPELINE_DEPTH),
+            Self::Copy => Ok(INGEST_TOTAL_ROWS),
+        }
+    }
+
+    /// Statement mode reported for this strategy: COPY sends no statements.
+    fn statement_mode(self) -> &'static str {
+        match self {
+            Self::MultiValues | Self::Pipelined => "prepared",
+            Self::Copy => "copy",
+        }
+    }
+}
+
+#[derive(Default)]
+struct IngestStatements {
+    multi_values: Option<PreparedStatement>,
+    multi_values_tail: Option<PreparedStatement>,
+    row: Option<PreparedStatement>,
+}
+
 #[derive(Clone, Copy, Debug, PartialEq, Eq)]
 enum ResultMode {
     CompleteOnly,
@@ -262,6 +418,21 @@ impl WorkloadSpec {
             },
         }
     }
+
+    /// Apply `--batch-size`, `--iterations`, and `--samples`; zero keeps the
+    /// workload default.
+    fn with_overrides(mut self, batch_size: usize, iterations: usize, samples: usize) -> Self {
+        if batch_size > 0 {
+            self.total_queries = batch_size;
+        }
+        if iterations > 0 {
+            self.iterations = iterations;
+        }
+        if samples > 0 {
+            self.latency_samples = samples;
+        }
+        self
+    }
 }
 
 #[derive(Clone, Copy, Debug, Default)]
@@ -297,6 +468,224 @@ struct LatencyResult {
     p99_ms: f64,
 }
 
+#[derive(Clone, Debug)]
+struct FaultResult {
+    baseline_qps: f64,
+    tail_qps: f64,
+    total: usize,
+    errors: usize,
+    fault_errors: usize,
+    /// Time from the end of the fault to the first successful query.
+    first_ok_ms: Option<f64>,
+    last_err_ms: f64,
+    last_error: Option<String>,
+}
+
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+enum OutputFormat {
+    Text,
+    Json,
+    Csv,
+}
+
+impl OutputFormat {
+    fn parse(s: &str) -> Result<Self, String> {
+        match s {
+            "text" => Ok(Self::Text),
+            "json" => Ok(Self::Json),
+            "csv" => Ok(Self::Csv),
+            other => Err(format!(
+                "unknown format '{}'; expected text | json | csv",
+                other
+            )),
+        }
+    }
+}
+
+/// Columns of every machine-readable record, in output order. Kept in sync
+/// with `Columns` in qailbench/record.go; a column that does not apply to a
+/// record is written as null in JSON and left empty in CSV.
+const RESULT_COLUMNS: [&str; 27] = [
+    "driver",
+    "mode",
+    "stmt_mode",
+    "workload",
+    "fault",
+    "qps",
+    "median_qps",
+    "p95_qps",
+    "rows_per_sec",
+    "mib_per_sec",
+    "checksum",
+    "p50_ms",
+    "p95_ms",
+    "p99_ms",
+    "avg_ms",
+    "baseline_qps",
+    "tail_qps",
+    "errors",
+    "fault_errors",
+    "total",
+    "first_ok_ms",
+    "last_err_ms",
+    "mem_unit",
+    "allocs_per_unit",
+    "bytes_per_unit",
+    "gc_cycles",
+    "gc_pause_ms",
+];
+
+#[derive(Clone, Debug)]
+enum RecordValue {
+    Text(String),
+    Float(f64),
+    Int(usize),
+}
+
+struct ResultRecord {
+    values: [Option<RecordValue>; RESULT_COLUMNS.len()],
+}
+
+impl ResultRecord {
+    fn new(mode: Mode, stmt_mode: &str, workload: &str) -> Self {
+        let mut record = Self {
+            values: std::array::from_fn(|_| None),
+        };
+        record.set("driver", RecordValue::Text("qail".to_string()));
+        record.set("mode", RecordValue::Text(mode.name().to_string()));
+        record.set("stmt_mode", RecordValue::Text(stmt_mode.to_string()));
+        record.set("workload", RecordValue::Text(workload.to_string()));
+        record
+    }
+
+    fn set(&mut self, column: &str, value: RecordValue) {
+        let idx = RESULT_COLUMNS
+            .iter()
+            .position(|name| *name == column)
+            .unwrap_or_else(|| panic!("unknown result column '{}'", column));
+        self.values[idx] = match value {
+            RecordValue::Float(v) if !v.is_finite() => None,
+            other => Some(other),
+        };
+    }
+
+    fn benchmark(
+        mode: Mode,
+        stmt_mode: &str,
+        workload: &str,
+        result: &BenchmarkResult,
+        has_checksum: bool,
+    ) -> Self {
+        let mut record = Self::new(mode, stmt_mode, workload);
+        record.set("qps", RecordValue::Float(result.qps));
+        if let Some(rows_per_sec) = result.rows_per_sec {
+            record.set("rows_per_sec", RecordValue::Float(rows_per_sec));
+        }
+        if let Some(mib_per_sec) = result.mib_per_sec {
+            record.set("mib_per_sec", RecordValue::Float(mib_per_sec));
+        }
+        if has_checksum {
+            record.set(
+                "checksum",
+                RecordValue::Text(format!("0x{:x}", result.checksum)),
+            );
+        }
+        record
+    }
+
+    fn fault(mode: Mode, kind: FaultKind, workload: &str, result: &FaultResult) -> Self {
+        // Fault workloads always run unprepared one-off queries.
+        let mut record = Self::new(mode, StatementMode::Unprepared.name(), workload);
+        record.set("fault", RecordValue::Text(kind.name().to_string()));
+        record.set("baseline_qps", RecordValue::Float(result.baseline_qps));
+        record.set("tail_qps", RecordValue::Float(result.tail_qps));
+        record.set("errors", RecordValue::Int(result.errors));
+        record.set("fault_errors", RecordValue::Int(result.fault_errors));
+        record.set("total", RecordValue::Int(result.total));
+        if let Some(first_ok_ms) = result.first_ok_ms {
+            record.set("first_ok_ms", RecordValue::Float(first_ok_ms));
+        }
+        record.set("last_err_ms", RecordValue::Float(result.last_err_ms));
+        record
+    }
+
+    fn latency(mode: Mode, stmt_mode: &str, workload: &str, result: &LatencyResult) -> Self {
+        let mut record = Self::new(mode, stmt_mode, workload);
+        record.set("p50_ms", RecordValue::Float(result.p50_ms));
+        record.set("p95_ms", RecordValue::Float(result.p95_ms));
+        record.set("p99_ms", RecordValue::Float(result.p99_ms));
+        record.set("avg_ms", RecordValue::Float(result.avg_ms));
+        record
+    }
+}
+
+fn json_escape(s: &str) -> String {
+    let mut out = String::with_capacity(s.len() + 2);
+    out.push('"');
+    for ch in s.chars() {
+        match ch {
+            '"' => out.push_str("\\\""),
+            '\\' => out.push_str("\\\\"),
+            '\n' => out.push_str("\\n"),
+            '\r' => out.push_str("\\r"),
+            '\t' => out.push_str("\\t"),
+            c if (c as u32) < 0x20 => out.push_str(&format!("\\u{:04x}", c as u32)),
+            c => out.push(c),
+        }
+    }
+    out.push('"');
+    out
+}
+
+fn csv_field(s: &str) -> String {
+    if s.contains([',', '"', '\n', '\r']) {
+        format!("\"{}\"", s.replace('"', "\"\""))
+    } else {
+        s.to_string()
+    }
+}
+
+/// Print records as JSON lines, or as CSV under a header of `RESULT_COLUMNS`.
+fn print_records(format: OutputFormat, records: &[ResultRecord]) {
+    match format {
+        OutputFormat::Text => {}
+        OutputFormat::Json => {
+            for record in records {
+                let fields: Vec<String> = RESULT_COLUMNS
+                    .iter()
+                    .zip(&record.values)
+                    .map(|(name, value)| {
+                        let encoded = match value {
+                            None => "null".to_string(),
+                            Some(RecordValue::Text(v)) => json_escape(v),
+                            Some(RecordValue::Float(v)) => format!("{}", v),
+                            Some(RecordValue::Int(v)) => v.to_string(),
+                        };
+                        format!("{}:{}", json_escape(name), encoded)
+                    })
+                    .collect();
+                println!("{{{}}}", fields.join(","));
+            }
+        }
+        OutputFormat::Csv => {
+            println!("{}", RESULT_COLUMNS.join(","));
+            for record in records {
+                let fields: Vec<String> = record
+                    .values
+                    .iter()
+                    .map(|value| match value {
+                        None => String::new(),
+                        Some(RecordValue::Text(v)) => csv_field(v),
+                        Some(RecordValue::Float(v)) => format!("{:.3}", v),
+                        Some(RecordValue::Int(v)) => v.to_string(),
+                    })
+                    .collect();
+                println!("{}", fields.join(","));
+            }
+        }
+    }
+}
+
 #[derive(Clone, Debug)]
 struct BenchDbConfig {
     host: String,
@@ -583,6 +972,81 @@ async fn ensure_bench_payload(conn: &mut PgConnection) -> Result<(), Box<dyn std
     Ok(())
 }
 
+async fn ensure_bench_ingest(conn: &mut PgConnection) -> Result<(), Box<dyn std::error::Error>> {
+    conn.execute_simple(BENCH_SETUP_LOCK_SQL).await?;
+    let setup_result = conn.execute_simple(CREATE_BENCH_INGEST_SQL).await;
+    let unlock_result = conn.execute_simple(BENCH_SETUP_UNLOCK_SQL).await;
+
+    setup_result?;
+    unlock_result?;
+    Ok(())
+}
+
+fn build_ingest_rows(total: usize) -> Vec<Vec<Option<Vec<u8>>>> {
+    (1..=total)
+        .map(|id| {
+            let note = (id % 5 != 0)
+                .then(|| format!("note-{:08}-{}", id, "x".repeat(id % 32)).into_bytes());
+            vec![
+                Some(id.to_string().into_bytes()),
+                Some(format!("ingest-{}", id).into_bytes()),
+                Some((id * 11).to_string().into_bytes()),
+                Some((id % 2 == 0).to_string().into_bytes()),
+                Some(format!("{:.3}", id as f64 / 7.0).into_bytes()),
+                note,
+            ]
+        })
+        .collect()
+}
+
+fn ingest_payload_bytes(rows: &[Vec<Option<Vec<u8>>>]) -> usize {
+    rows.iter()
+        .flatten()
+        .map(|value| value.as_ref().map_or(0, Vec::len))
+        .sum()
+}
+
+fn build_ingest_multi_values_sql(row_count: usize) -> String {
+    let mut sql = format!(
+        "INSERT INTO {} ({}) VALUES ",
+        INGEST_TABLE,
+        INGEST_COLUMNS.join(", ")
+    );
+    for row in 0..row_count {
+        if row > 0 {
+            sql.push_str(", ");
+        }
+        let base = row * INGEST_COLUMNS.len();
+        sql.push_str(&format!(
+            "(${}::int, ${}::text, ${}::int, ${}::bool, ${}::numeric, ${}::text)",
+            base + 1,
+            base + 2,
+            base + 3,
+            base + 4,
+            base + 5,
+            base + 6
+        ));
+    }
+    sql
+}
+
+fn encode_ingest_copy_rows(rows: &[Vec<Option<Vec<u8>>>], payload_bytes: usize) -> Vec<u8> {
+    let mut buf = Vec::with_capacity(payload_bytes + rows.len() * INGEST_COLUMNS.len());
+    for row in rows {
+        for (idx, value) in row.iter().enumerate() {
+            if idx > 0 {
+                buf.push(b'\t');
+            }
+            match value {
+                Some(bytes) => buf.extend_from_slice(bytes),
+                None => buf.extend_from_slice(b"\\N"),
+            }
+        }
+        buf.push(b'\n');
+    }
+    buf
+}
+
 async fn ensure_workload_ready(
     cfg: &BenchDbConfig,
     spec: WorkloadSpec,
@@ -1158,6 +1622,182 @@ async fn run_pool10_mode(
     Ok(make_benchmark_result(aggregate, elapsed))
 }
 
+async fn run_ingest_multi_values_once(
+    conn: &mut PgConnection,
+    stmts: &IngestStatements,
+    chunk_params: &[Vec<Option<Vec<u8>>>],
+    rows_per_stmt: usize,
+) -> Result<BatchStats, Box<dyn std::error::Error>> {
+    let mut stats = BatchStats::default();
+    for params in chunk_params {
+        let stmt = if params.len() == rows_per_stmt * INGEST_COLUMNS.len() {
+            stmts.multi_values.as_ref()
+        } else {
+            stmts.multi_values_tail.as_ref()
+        };
+        let stmt = stmt.ok_or_else(|| "missing prepared multi-VALUES statement".to_string())?;
+        conn.query_prepared_single_count(stmt, params).await?;
+        stats.completed += 1;
+    }
+    Ok(stats)
+}
+
+async fn run_ingest_pipelined_once(
+    conn: &mut PgConnection,
+    stmts: &IngestStatements,
+    rows: &[Vec<Option<Vec<u8>>>],
+    depth: usize,
+) -> Result<BatchStats, Box<dyn std::error::Error>> {
+    let stmt = stmts
+        .row
+        .as_ref()
+        .ok_or_else(|| "missing prepared row INSERT statement".to_string())?;
+    let mut stats = BatchStats::default();
+    for chunk in rows.chunks(depth) {
+        let completed = conn.pipeline_execute_prepared_count(stmt, chunk).await?;
+        if completed != chunk.len() {
+            return Err(format!(
+                "pipeline completed {} inserts, expected {}",
+                completed,
+                chunk.len()
+            )
+            .into());
+        }
+        stats.completed += completed;
+    }
+    Ok(stats)
+}
+
+/// Loads rows with one COPY per chunk of `rows_per_copy` rows.
+async fn run_ingest_copy_once(
+    conn: &mut PgConnection,
+    rows: &[Vec<Option<Vec<u8>>>],
+    rows_per_copy: usize,
+) -> Result<BatchStats, Box<dyn std::error::Error>> {
+    let columns: Vec<String> = INGEST_COLUMNS.iter().map(|c| c.to_string()).collect();
+    let mut stats = BatchStats::default();
+    for chunk in rows.chunks(rows_per_copy) {
+        let data = encode_ingest_copy_rows(chunk, ingest_payload_bytes(chunk));
+        conn.copy_in_raw(INGEST_TABLE, &columns, &data).await?;
+        stats.completed += 1;
+    }
+    Ok(stats)
+}
+
+/// Truncates the ingest table, runs one timed load, and verifies the row
+/// count outside the timed window.
+async fn run_ingest_iteration(
+    conn: &mut PgConnection,
+    strategy: IngestStrategy,
+    stmts: &IngestStatements,
+    rows: &[Vec<Option<Vec<u8>>>],
+    chunk_params: &[Vec<Option<Vec<u8>>>],
+    batch_size: usize,
+    payload_bytes: usize,
+) -> Result<(BatchStats, Duration), Box<dyn std::error::Error>> {
+    conn.execute_simple(TRUNCATE_BENCH_INGEST_SQL).await?;
+
+    let start = Instant::now();
+    let mut stats = match strategy {
+        IngestStrategy::MultiValues => {
+            run_ingest_multi_values_once(conn, stmts, chunk_params, batch_size).await?
+        }
+        IngestStrategy::Pipelined => {
+            run_ingest_pipelined_once(conn, stmts, rows, batch_size).await?
+        }
+        IngestStrategy::Copy => run_ingest_copy_once(conn, rows, batch_size).await?,
+    };
+    let elapsed = start.elapsed();
+
+    let inserted = parse_first_i64(
+        &conn
+            .query_rows_with_result_format(COUNT_BENCH_INGEST_SQL, &[], PgEncoder::FORMAT_TEXT)
+            .await?,
+    )?;
+    if inserted != rows.len() as i64 {
+        return Err(format!(
+            "{} inserted {} rows, expected {}",
+            strategy.name(),
+            inserted,
+            rows.len()
+        )
+        .into());
+    }
+
+    stats.rows = rows.len();
+    stats.bytes = payload_bytes;
+    Ok((stats, elapsed))
+}
+
+async fn run_ingest_mode(
+    cfg: &BenchDbConfig,
+    strategy: IngestStrategy,
+    batch_size: usize,
+    iterations: usize,
+) -> Result<BenchmarkResult, Box<dyn std::error::Error>> {
+    let mut conn = connect_bench_connection(cfg).await?;
+    ensure_bench_ingest(&mut conn).await?;
+
+    let rows = build_ingest_rows(INGEST_TOTAL_ROWS);
+    let payload_bytes = ingest_payload_bytes(&rows);
+
+    let mut stmts = IngestStatements::default();
+    let mut chunk_params = Vec::new();
+    match strategy {
+        IngestStrategy::MultiValues => {
+            stmts.multi_values = Some(
+                conn.prepare(&build_ingest_multi_values_sql(batch_size))
+                    .await?,
+            );
+            let tail = rows.len() % batch_size;
+            if tail > 0 {
+                stmts.multi_values_tail =
+                    Some(conn.prepare(&build_ingest_multi_values_sql(tail)).await?);
+            }
+            // Flatten once up front so only the statements are timed; qailbench
+            // does the same for pgx and lib/pq.
+            chunk_params = rows
+                .chunks(batch_size)
+                .map(|chunk| chunk.concat())
+                .collect();
+        }
+        IngestStrategy::Pipelined => {
+            stmts.row = Some(conn.prepare(INGEST_ROW_SQL).await?);
+        }
+        IngestStrategy::Copy => {}
+    }
+
+    run_ingest_iteration(
+        &mut conn,
+        strategy,
+        &stmts,
+        &rows,
+        &chunk_params,
+        batch_size,
+        payload_bytes,
+    )
+    .await?;
+
+    let mut total = Duration::ZERO;
+    let mut aggregate = BatchStats::default();
+    for _ in 0..iterations {
+        let (stats, elapsed) = run_ingest_iteration(
+            &mut conn,
+            strategy,
+            &stmts,
+            &rows,
+            &chunk_params,
+            batch_size,
+            payload_bytes,
+        )
+        .await?;
+        total += elapsed;
+        aggregate.add(stats);
+    }
+
+    Ok(make_benchmark_result(aggregate, total))
+}
+
 fn consume_scalar_value(value: Option<&[u8]>, stats: &mut BatchStats) {
     stats.rows += 1;
     if let Some(value) = value {
@@ -1236,107 +1876,389 @@ fn make_benchmark_result(stats: BatchStats, elapsed: Duration) -> BenchmarkResul
     }
 }
 
-#[tokio::main]
-async fn main() -> Result<(), Box<dyn std::error::Error>> {
-    let mut mode: Option<Mode> = None;
-    let mut workload = Workload::Point;
-    let mut statement_mode = StatementMode::Prepared;
-    let mut plain = false;
-    let mut expect_workload = false;
-    let mut expect_statement_mode = false;
+fn print_benchmark_text(
+    mode: Mode,
+    statement_mode: StatementMode,
+    spec: WorkloadSpec,
+    result: &BenchmarkResult,
+) {
+    print!(
+        "qail {}/{}/{}: {:.0} q/s",
+        mode.name(),
+        statement_mode.name(),
+        spec.name,
+        result.qps
+    );
+    if let Some(rows_per_sec) = result.rows_per_sec {
+        print!(" | {:.0} rows/s", rows_per_sec);
+    }
+    if let Some(mib_per_sec) = result.mib_per_sec {
+        print!(" | {:.2} MiB/s", mib_per_sec);
+    }
+    if spec.result_mode != ResultMode::CompleteOnly {
+        print!(" | checksum=0x{:x}", result.checksum);
+    }
+    println!();
+}
 
-    for arg in std::env::args().skip(1) {
-        if expect_workload {
-            workload = Workload::parse(&arg)?;
-            expect_workload = false;
-            continue;
-        }
-        if expect_statement_mode {
-            statement_mode = StatementMode::parse(&arg)?;
-            expect_statement_mode = false;
-            continue;
-        }
-        if arg == "--plain" {
-            plain = true;
-            continue;
+/// Addresses of the qailbench fault proxy: the listener the pool connects
+/// through and the control listener that injects faults (see
+/// `FaultProxy.ServeControl` in qailbench/fault.go).
+struct FaultProxyConfig {
+    host: String,
+    port: u16,
+    control: String,
+}
+
+impl FaultProxyConfig {
+    fn from_env() -> Result<Self, String> {
+        let proxy = std::env::var("QAIL_BENCH_FAULT_PROXY")
+            .map_err(|_| "fault mode needs QAIL_BENCH_FAULT_PROXY=host:port".to_string())?;
+        let control = std::env::var("QAIL_BENCH_FAULT_CONTROL")
+            .map_err(|_| "fault mode needs QAIL_BENCH_FAULT_CONTROL=host:port".to_string())?;
+        let (host, port) = proxy
+            .rsplit_once(':')
+            .ok_or_else(|| format!("invalid QAIL_BENCH_FAULT_PROXY '{}'", proxy))?;
+        let port = port
+            .parse()
+            .map_err(|_| format!("invalid QAIL_BENCH_FAULT_PROXY port in '{}'", proxy))?;
+        Ok(Self {
+            host: host.to_string(),
+            port,
+            control,
+        })
+    }
+
+    async fn set_fault(
+        &self,
+        kind: FaultKind,
+        active: bool,
+    ) -> Result<(), Box<dyn std::error::Error>> {
+        let state = if active { "on" } else { "off" };
+        let (reader, mut writer) = TcpStream::connect(&self.control).await?.into_split();
+        writer
+            .write_all(format!("{} {}\n", kind.name(), state).as_bytes())
+            .await?;
+        let mut reply = String::new();
+        BufReader::new(reader).read_line(&mut reply).await?;
+        match reply.trim() {
+            "ok" => Ok(()),
+            other => Err(format!(
+                "fault proxy rejected '{} {}': {}",
+                kind.name(),
+                state,
+                other
+            )
+            .into()),
         }
-        if arg == "--workload" || arg == "--scenario" {
-            expect_workload = true;
+    }
+}
+
+struct FaultSample {
+    done: Duration,
+    err: Option<String>,
+}
+
+/// Run one unprepared query on a pooled connection. A connection broken by
+/// the fault fails its reset on release and is dropped, so the pool has to
+/// reconnect for later queries.
+async fn run_fault_query(
+    pool: &PgPool,
+    spec: WorkloadSpec,
+    params: &Vec<Option<Vec<u8>>>,
+) -> Result<(), String> {
+    let mut pooled = pool.acquire_system().await.map_err(|e| e.to_string())?;
+    let result = match pooled.get_mut() {
+        Ok(conn) => run_single_iteration(
+            conn,
+            spec.sql,
+            None,
+            std::slice::from_ref(params),
+            spec.result_mode,
+            StatementMode::Unprepared,
+        )
+        .await
+        .map(|_| ())
+        .map_err(|e| e.to_string()),
+        Err(e) => Err(e.to_string()),
+    };
+    pooled.release().await;
+    result
+}
+
+/// Run the workload from POOL_SIZE workers through the fault proxy for the
+/// lead, fault, and tail windows, injecting `kind` in the middle one, and
+/// score how the pool rode it out the same way qailbench does for pgx.
+async fn run_fault_mode(
+    cfg: &BenchDbConfig,
+    proxy: &FaultProxyConfig,
+    spec: WorkloadSpec,
+    kind: FaultKind,
+    params: Vec<Vec<Option<Vec<u8>>>>,
+) -> Result<FaultResult, Box<dyn std::error::Error>> {
+    ensure_workload_ready(cfg, spec).await?;
+    let mut proxied = cfg.clone();
+    proxied.host = proxy.host.clone();
+    proxied.port = proxy.port;
+    let pool = connect_bench_pool(&proxied).await?;
+
+    let params = Arc::new(params);
+    let stop = Arc::new(AtomicBool::new(false));
+    let start = Instant::now();
+    let mut tasks = JoinSet::new();
+    for worker in 0..POOL_SIZE {
+        let pool = pool.clone();
+        let params = Arc::clone(&params);
+        let stop = Arc::clone(&stop);
+        tasks.spawn(async move {
+            let mut samples = Vec::with_capacity(4096);
+            let mut idx = worker;
+            while !stop.load(Ordering::Relaxed) {
+                let outcome = tokio::time::timeout(
+                    FAULT_QUERY_TIMEOUT,
+                    run_fault_query(&pool, spec, &params[idx % params.len()]),
+                )
+                .await;
+                if stop.load(Ordering::Relaxed) {
+                    break;
+                }
+                let err = match outcome {
+                    Ok(Ok(())) => None,
+                    Ok(Err(err)) => Some(err),
+                    Err(_) => Some(format!("query timed out after {:?}", FAULT_QUERY_TIMEOUT)),
+                };
+                let failed = err.is_some();
+                samples.push(FaultSample {
+                    done: start.elapsed(),
+                    err,
+                });
+                if failed {
+                    // Avoid spinning on instant connection refusals.
+                    tokio::time::sleep(FAULT_ERROR_BACKOFF).await;
+                }
+                idx += POOL_SIZE;
+            }
+            samples
+        });
+    }
+
+    let schedule = async {
+        tokio::time::sleep(FAULT_LEAD_TIME).await;
+        let fault_start = start.elapsed();
+        proxy.set_fault(kind, true).await?;
+        tokio::time::sleep(FAULT_DURATION).await;
+        proxy.set_fault(kind, false).await?;
+        let fault_end = start.elapsed();
+        tokio::time::sleep(FAULT_TAIL_TIME).await;
+        Ok::<_, Box<dyn std::error::Error>>((fault_start, fault_end, start.elapsed()))
+    }
+    .await;
+    stop.store(true, Ordering::Relaxed);
+
+    let mut samples = Vec::with_capacity(POOL_SIZE * 4096);
+    while let Some(joined) = tasks.join_next().await {
+        samples.extend(joined?);
+    }
+    let (fault_start, fault_end, run_end) = schedule?;
+    Ok(score_fault_samples(
+        samples,
+        fault_start,
+        fault_end,
+        run_end,
+    ))
+}
+
+fn score_fault_samples(
+    mut samples: Vec<FaultSample>,
+    fault_start: Duration,
+    fault_end: Duration,
+    run_end: Duration,
+) -> FaultResult {
+    samples.sort_by_key(|sample| sample.done);
+
+    let mut errors = 0;
+    let mut fault_errors = 0;
+    let mut baseline_ok = 0;
+    let mut first_ok = None;
+    let mut last_err = fault_end;
+    let mut last_error = None;
+    for sample in &samples {
+        if let Some(err) = &sample.err {
+            errors += 1;
+            last_error = Some(err.clone());
+            if sample.done >= fault_start && sample.done < fault_end {
+                fault_errors += 1;
+            }
+            if sample.done >= fault_end {
+                last_err = sample.done;
+            }
             continue;
         }
-        if arg == "--statement-mode" || arg == "--stmt-mode" {
-            expect_statement_mode = true;
-            continue;
+        if sample.done < fault_start {
+            baseline_ok += 1;
         }
-        if mode.is_none() {
-            mode = Some(Mode::parse(&arg)?);
-            continue;
+        if sample.done >= fault_end && first_ok.is_none() {
+            first_ok = Some(sample.done);
         }
-        return Err(format!("unexpected argument '{}'", arg).into());
     }
 
-    if expect_workload {
-        return Err("missing workload value after --workload".into());
-    }
-    if expect_statement_mode {
-        return Err("missing statement mode after --statement-mode".into());
+    let tail_ok = samples
+        .iter()
+        .filter(|sample| sample.err.is_none() && sample.done > last_err)
+        .count();
+    let tail_window = run_end.saturating_sub(last_err);
+    FaultResult {
+        baseline_qps: baseline_ok as f64 / fault_start.as_secs_f64(),
+        tail_qps: if tail_window.is_zero() {
+            0.0
+        } else {
+            tail_ok as f64 / tail_window.as_secs_f64()
+        },
+        total: samples.len(),
+        errors,
+        fault_errors,
+        first_ok_ms: first_ok.map(|done| (done - fault_end).as_secs_f64() * 1000.0),
+        last_err_ms: (last_err - fault_end).as_secs_f64() * 1000.0,
+        last_error,
     }
+}
 
-    let mode = mode
-        .ok_or_else(|| "missing mode argument: single | pipeline | pool10 | latency".to_string())?;
-    let cfg = BenchDbConfig::from_env()?;
-    let spec = WorkloadSpec::new(workload);
-    let params = build_param_batch(spec);
+fn flag_value(args: &mut impl Iterator<Item = String>, flag: &str) -> Result<String, String> {
+    args.next()
+        .ok_or_else(|| format!("missing value after {}", flag))
+}
 
-    match mode {
-        Mode::Single => {
-            let result = run_single_mode(&cfg, spec, statement_mode, &params).await?;
-            if plain {
-                println!("{:.3}", result.qps);
-            } else {
-                print!(
-                    "qail {}/{}/{}: {:.0} q/s",
-                    mode.name(),
-                    statement_mode.name(),
-                    spec.name,
-                    result.qps
-                );
-                if let Some(rows_per_sec) = result.rows_per_sec {
-                    print!(" | {:.0} rows/s", rows_per_sec);
-                }
-                if let Some(mib_per_sec) = result.mib_per_sec {
-                    print!(" | {:.2} MiB/s", mib_per_sec);
-                }
-                if spec.result_mode != ResultMode::CompleteOnly {
-                    print!(" | checksum=0x{:x}", result.checksum);
-                }
-                println!();
+fn parse_count(flag: &str, value: &str) -> Result<usize, String> {
+    value
+        .parse()
+        .map_err(|_| format!("invalid {} value '{}'", flag, value))
+}
+
+#[tokio::main]
+async fn main() -> Result<(), Box<dyn std::error::Error>> {
+    let mut mode: Option<Mode> = None;
+    let mut workload_name: Option<String> = None;
+    let mut statement_mode = StatementMode::Prepared;
+    let mut fault_kind = FaultKind::Reset;
+    let mut format = OutputFormat::Text;
+    let mut plain = false;
+    let mut batch_size = 0;
+    let mut iterations = 0;
+    let mut samples = 0;
+    let mut set_flags: Vec<&'static str> = Vec::new();
+
+    let mut args = std::env::args().skip(1);
+    while let Some(arg) = args.next() {
+        match arg.as_str() {
+            "--plain" => {
+                plain = true;
+                set_flags.push("--plain");
+            }
+            "--workload" | "--scenario" => {
+                workload_name = Some(flag_value(&mut args, &arg)?);
+                set_flags.push("--workload");
+            }
+            "--statement-mode" | "--stmt-mode" => {
+                statement_mode = StatementMode::parse(&flag_value(&mut args, &arg)?)?;
+                set_flags.push("--stmt-mode");
+            }
+            "--fault" => {
+                fault_kind = FaultKind::parse(&flag_value(&mut args, &arg)?)?;
+                set_flags.push("--fault");
+            }
+            "--format" => format = OutputFormat::parse(&flag_value(&mut args, &arg)?)?,
+            "--batch-size" => {
+                batch_size = parse_count(&arg, &flag_value(&mut args, &arg)?)?;
+                set_flags.push("--batch-size");
             }
+            "--iterations" => {
+                iterations = parse_count(&arg, &flag_value(&mut args, &arg)?)?;
+                set_flags.push("--iterations");
+            }
+            "--samples" => {
+                samples = parse_count(&arg, &flag_value(&mut args, &arg)?)?;
+                set_flags.push("--samples");
+            }
+            _ if mode.is_none() => mode = Some(Mode::parse(&arg)?),
+            _ => return Err(format!("unexpected argument '{}'", arg).into()),
         }
-        Mode::Pipeline => {
-            let result = run_pipeline_mode(&cfg, spec, statement_mode, &params).await?;
-            if plain {
-                println!("{:.3}", result.qps);
+    }
+
+    let mode = mode.ok_or_else(|| {
+        "missing mode argument: single | pipeline | pool10 | latency | ingest | fault".to_string()
+    })?;
+    // Reject flags the selected mode would ignore instead of dropping them.
+    if let Some(flag) = set_flags
+        .iter()
+        .find(|flag| !mode.accepted_flags().contains(flag))
+    {
+        return Err(format!("{} is not used by mode {}", flag, mode.name()).into());
+    }
+    if plain && format != OutputFormat::Text {
+        return Err("--plain only applies to --format text".into());
+    }
+    if plain && matches!(mode, Mode::Ingest) && workload_name.is_none() {
+        return Err(
+            "--plain with ingest prints one number and needs --workload multi_values | pipelined | copy"
+                .into(),
+        );
+    }
+    let cfg = BenchDbConfig::from_env()?;
+    let mut records = Vec::new();
+
+    if let Mode::Ingest = mode {
+        let strategies = match workload_name.as_deref() {
+            Some(name) => vec![IngestStrategy::parse(name)?],
+            None => IngestStrategy::ALL.to_vec(),
+        };
+        let batch_sizes = strategies
+            .iter()
+            .map(|strategy| strategy.batch_size(batch_size))
+            .collect::<Result<Vec<_>, _>>()?;
+        let iterations = if iterations > 0 {
+            iterations
+        } else {
+            INGEST_ITERATIONS
+        };
+        for (strategy, batch_size) in strategies.into_iter().zip(batch_sizes) {
+            let result = run_ingest_mode(&cfg, strategy, batch_size, iterations).await?;
+            let rows_per_sec = result.rows_per_sec.unwrap_or_default();
+            if format != OutputFormat::Text {
+                records.push(ResultRecord::benchmark(
+                    mode,
+                    strategy.statement_mode(),
+                    strategy.name(),
+                    &result,
+                    false,
+                ));
+            } else if plain {
+                println!("{:.3}", rows_per_sec);
             } else {
-                print!(
-                    "qail {}/{}/{}: {:.0} q/s",
+                println!(
+                    "qail {}/{}: {:.0} rows/s | {:.2} MiB/s | {:.0} stmt/s",
                     mode.name(),
-                    statement_mode.name(),
-                    spec.name,
+                    strategy.name(),
+                    rows_per_sec,
+                    result.mib_per_sec.unwrap_or_default(),
                     result.qps
                 );
-                if let Some(rows_per_sec) = result.rows_per_sec {
-                    print!(" | {:.0} rows/s", rows_per_sec);
-                }
-                if let Some(mib_per_sec) = result.mib_per_sec {
-                    print!(" | {:.2} MiB/s", mib_per_sec);
-                }
-                if spec.result_mode != ResultMode::CompleteOnly {
-                    print!(" | checksum=0x{:x}", result.checksum);
-                }
-                println!();
             }
         }
+        print_records(format, &records);
+        return Ok(());
+    }
+
+    let workload = workload_name
+        .as_deref()
+        .map(Workload::parse)
+        .transpose()?
+        .unwrap_or(Workload::Point);
+    let spec = WorkloadSpec::new(workload).with_overrides(batch_size, iterations, samples);
+    let params = build_param_batch(spec);
+
+    let result = match mode {
+        Mode::Single => run_single_mode(&cfg, spec, statement_mode, &params).await?,
+        Mode::Pipeline => run_pipeline_mode(&cfg, spec, statement_mode, &params).await?,
         Mode::Pool10 => {
             if !params.len().is_multiple_of(POOL_SIZE) {
                 return Err(format!(
@@ -1352,33 +2274,20 @@ async fn main() -> Result<(), Box<dyn std::error::Error>> {
                 .chunks(per_worker)
                 .map(|chunk| chunk.to_vec())
                 .collect();
-            let result = run_pool10_mode(&cfg, spec, statement_mode, worker_params).await?;
-            if plain {
-                println!("{:.3}", result.qps);
-            } else {
-                print!(
-                    "qail {}/{}/{}: {:.0} q/s",
-                    mode.name(),
-                    statement_mode.name(),
-                    spec.name,
-                    result.qps
-                );
-                if let Some(rows_per_sec) = result.rows_per_sec {
-                    print!(" | {:.0} rows/s", rows_per_sec);
-                }
-                if let Some(mib_per_sec) = result.mib_per_sec {
-                    print!(" | {:.2} MiB/s", mib_per_sec);
-                }
-                if spec.result_mode != ResultMode::CompleteOnly {
-                    print!(" | checksum=0x{:x}", result.checksum);
-                }
-                println!();
-            }
+            run_pool10_mode(&cfg, spec, statement_mode, worker_params).await?
         }
         Mode::Latency => {
             ensure_workload_ready(&cfg, spec).await?;
             let result = run_latency_mode(&cfg, spec, statement_mode, &params).await?;
-            if plain {
+            if format != OutputFormat::Text {
+                records.push(ResultRecord::latency(
+                    mode,
+                    statement_mode.name(),
+                    spec.name,
+                    &result,
+                ));
+                print_records(format, &records);
+            } else if plain {
                 println!(
                     "{:.6},{:.6},{:.6},{:.6}",
                     result.p50_ms, result.p95_ms, result.p99_ms, result.avg_ms
@@ -1395,7 +2304,61 @@ async fn main() -> Result<(), Box<dyn std::error::Error>> {
                     result.avg_ms
                 );
             }
+            return Ok(());
+        }
+        Mode::Fault => {
+            let proxy = FaultProxyConfig::from_env()?;
+            let result = run_fault_mode(&cfg, &proxy, spec, fault_kind, params).await?;
+            if format != OutputFormat::Text {
+                records.push(ResultRecord::fault(mode, fault_kind, spec.name, &result));
+                print_records(format, &records);
+            } else if plain {
+                println!(
+                    "{:.6},{:.6},{},{}",
+                    result.first_ok_ms.unwrap_or(-1.0),
+                    result.last_err_ms,
+                    result.errors,
+                    result.total
+                );
+            } else {
+                let first_ok = result
+                    .first_ok_ms
+                    .map_or_else(|| "never".to_string(), |ms| format!("{:.3} ms", ms));
+                println!(
+                    "qail {}/{}/{}: baseline={:.0} q/s | tail={:.0} q/s | errors={}/{} ({} during fault) | first_ok={} | last_err={:.3} ms",
+                    mode.name(),
+                    fault_kind.name(),
+                    spec.name,
+                    result.baseline_qps,
+                    result.tail_qps,
+                    result.errors,
+                    result.total,
+                    result.fault_errors,
+                    first_ok,
+                    result.last_err_ms
+                );
+                if let Some(err) = &result.last_error {
+                    println!("  last error: {}", err);
+                }
+            }
+            return Ok(());
         }
+        Mode::Ingest => unreachable!("ingest mode returns before workload setup"),
+    };
+
+    if format != OutputFormat::Text {
+        records.push(ResultRecord::benchmark(
+            mode,
+            statement_mode.name(),
+            spec.name,
+            &result,
+            spec.result_mode != ResultMode::CompleteOnly,
+        ));
+        print_records(format, &records);
+    } else if plain {
+        println!("{:.3}", result.qps);
+    } else {
+        print_benchmark_text(mode, statement_mode, spec, &result);
     }
 
     Ok(())
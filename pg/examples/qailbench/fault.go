@@ -0,0 +1,320 @@
+package qailbench
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// FaultProxy is a TCP relay between the benchmark and the server that can
+// inject connection-level failures while a workload is running. Faults are
+// set directly with SetFault, or by out-of-process drivers through
+// ServeControl.
+type FaultProxy struct {
+	listener  net.Listener
+	upstream  string
+	mu        sync.Mutex
+	cond      *sync.Cond
+	conns     map[net.Conn]struct{}
+	down      bool
+	blackhole bool
+	closed    bool
+}
+
+// NewFaultProxy relays connections accepted on listenAddr to upstream.
+func NewFaultProxy(listenAddr, upstream string) (*FaultProxy, error) {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return nil, err
+	}
+	p := &FaultProxy{
+		listener: listener,
+		upstream: upstream,
+		conns:    map[net.Conn]struct{}{},
+	}
+	p.cond = sync.NewCond(&p.mu)
+	go p.acceptLoop()
+	return p, nil
+}
+
+func (p *FaultProxy) Addr() *net.TCPAddr {
+	return p.listener.Addr().(*net.TCPAddr)
+}
+
+func (p *FaultProxy) acceptLoop() {
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(client)
+	}
+}
+
+func (p *FaultProxy) handle(client net.Conn) {
+	p.mu.Lock()
+	refuse := p.down || p.closed
+	p.mu.Unlock()
+	if refuse {
+		client.Close()
+		return
+	}
+
+	server, err := net.Dial("tcp", p.upstream)
+	if err != nil {
+		client.Close()
+		return
+	}
+
+	p.mu.Lock()
+	if p.down || p.closed {
+		p.mu.Unlock()
+		client.Close()
+		server.Close()
+		return
+	}
+	p.conns[client] = struct{}{}
+	p.conns[server] = struct{}{}
+	p.mu.Unlock()
+
+	go p.pipe(server, client)
+	p.pipe(client, server)
+}
+
+func (p *FaultProxy) pipe(dst, src net.Conn) {
+	defer func() {
+		p.mu.Lock()
+		delete(p.conns, dst)
+		delete(p.conns, src)
+		p.mu.Unlock()
+		dst.Close()
+		src.Close()
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if !p.waitForward() {
+				return
+			}
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// waitForward blocks while the proxy is blackholed and reports whether the
+// relay should keep forwarding.
+func (p *FaultProxy) waitForward() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.blackhole && !p.closed && !p.down {
+		p.cond.Wait()
+	}
+	return !p.closed && !p.down
+}
+
+func (p *FaultProxy) SetFault(kind FaultKind, active bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch kind {
+	case FaultReset:
+		p.down = active
+		if active {
+			for conn := range p.conns {
+				conn.Close()
+			}
+			p.conns = map[net.Conn]struct{}{}
+		}
+	case FaultBlackhole:
+		p.blackhole = active
+	}
+	p.cond.Broadcast()
+}
+
+func (p *FaultProxy) Close() {
+	p.mu.Lock()
+	p.closed = true
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = map[net.Conn]struct{}{}
+	p.cond.Broadcast()
+	p.mu.Unlock()
+	p.listener.Close()
+}
+
+// ServeControl accepts control connections on listener until it is closed.
+// Each request is one line, "<reset|blackhole> <on|off>", answered with "ok"
+// or "error: <reason>".
+func (p *FaultProxy) ServeControl(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go p.handleControl(conn)
+	}
+}
+
+func (p *FaultProxy) handleControl(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if err := p.applyControl(scanner.Text()); err != nil {
+			fmt.Fprintf(conn, "error: %v\n", err)
+			continue
+		}
+		fmt.Fprintln(conn, "ok")
+	}
+}
+
+func (p *FaultProxy) applyControl(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return fmt.Errorf("expected \"<fault> <on|off>\", got %q", line)
+	}
+	kind, err := ParseFaultKind(fields[0])
+	if err != nil {
+		return err
+	}
+	switch fields[1] {
+	case "on":
+		p.SetFault(kind, true)
+	case "off":
+		p.SetFault(kind, false)
+	default:
+		return fmt.Errorf("expected on or off, got %q", fields[1])
+	}
+	return nil
+}
+
+// upstreamAddr returns the server address a fault proxy relays to for
+// connString, which must name a TCP host.
+func upstreamAddr(connString string) (string, error) {
+	cfg, err := pgconn.ParseConfig(connString)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(cfg.Host, "/") {
+		return "", fmt.Errorf("fault mode needs a TCP host, got unix socket %q", cfg.Host)
+	}
+	return net.JoinHostPort(cfg.Host, strconv.Itoa(int(cfg.Port))), nil
+}
+
+type faultSample struct {
+	done time.Duration
+	ok   bool
+	err  error
+}
+
+// runFaultWorkload runs query from poolSize workers for the lead, fault, and
+// tail windows, injecting kind through proxy in the middle one, and scores
+// how the driver rode it out. Each query gets faultQueryTimeout to finish.
+func runFaultWorkload(ctx context.Context, proxy *FaultProxy, kind FaultKind, query func(ctx context.Context, i int) error) FaultResult {
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	start := time.Now()
+	samplesCh := make(chan []faultSample, poolSize)
+	var wg sync.WaitGroup
+	for w := 0; w < poolSize; w++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			samples := make([]faultSample, 0, 4096)
+			for i := idx; runCtx.Err() == nil; i += poolSize {
+				queryCtx, cancel := context.WithTimeout(runCtx, faultQueryTimeout)
+				err := query(queryCtx, i)
+				cancel()
+				if runCtx.Err() != nil {
+					break
+				}
+				samples = append(samples, faultSample{done: time.Since(start), ok: err == nil, err: err})
+				if err != nil {
+					// Avoid spinning on instant connection refusals.
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+			samplesCh <- samples
+		}(w)
+	}
+
+	time.Sleep(faultLeadTime)
+	faultStart := time.Since(start)
+	proxy.SetFault(kind, true)
+	time.Sleep(faultDuration)
+	proxy.SetFault(kind, false)
+	faultEnd := time.Since(start)
+	time.Sleep(faultTailTime)
+	runEnd := time.Since(start)
+	stop()
+	wg.Wait()
+	close(samplesCh)
+
+	all := make([]faultSample, 0, 4096)
+	for samples := range samplesCh {
+		all = append(all, samples...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].done < all[j].done
+	})
+
+	result := FaultResult{Total: len(all)}
+	baselineOK := 0
+	var firstOK, lastErr time.Duration
+	for _, sample := range all {
+		if !sample.ok {
+			result.Errors++
+			result.LastErrMessage = sample.err.Error()
+			if sample.done >= faultStart && sample.done < faultEnd {
+				result.FaultErrors++
+			}
+			if sample.done >= faultEnd {
+				lastErr = sample.done
+			}
+			continue
+		}
+		if sample.done < faultStart {
+			baselineOK++
+		}
+		if sample.done >= faultEnd && !result.Recovered {
+			result.Recovered = true
+			firstOK = sample.done
+		}
+	}
+
+	if lastErr < faultEnd {
+		lastErr = faultEnd
+	}
+	tailOK := 0
+	for _, sample := range all {
+		if sample.ok && sample.done > lastErr {
+			tailOK++
+		}
+	}
+
+	result.BaselineQPS = float64(baselineOK) / faultStart.Seconds()
+	if tailWindow := runEnd - lastErr; tailWindow > 0 {
+		result.TailQPS = float64(tailOK) / tailWindow.Seconds()
+	}
+	if result.Recovered {
+		result.FirstOKMs = (firstOK - faultEnd).Seconds() * 1000.0
+	}
+	result.LastErrorMs = (lastErr - faultEnd).Seconds() * 1000.0
+	return result
+}
@@ -0,0 +1,842 @@
+package qailbench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pgx runs cases through jackc/pgx v5, using the pgconn pipeline API for the
+// once and pipeline modes and pgxpool for pool10 and fault.
+type Pgx struct {
+	ConnString string
+}
+
+func (d Pgx) Name() string {
+	return "pgx"
+}
+
+func (d Pgx) Run(ctx context.Context, c Case) (Result, error) {
+	switch c.Mode {
+	case "once":
+		calls, templates, ordered, err := c.onceWorkload()
+		if err != nil {
+			return Result{}, err
+		}
+		return d.runPreparedPipeline(ctx, calls, templates, ordered, resultModePointRows, c.iterationsOr(OnceIterations), nil)
+	case "single", "pipeline", "pool10":
+		spec, err := c.modeWorkload()
+		if err != nil {
+			return Result{}, err
+		}
+		switch c.Mode {
+		case "single":
+			return d.runSingle(ctx, spec, c.StmtMode)
+		case "pipeline":
+			return d.runPipeline(ctx, spec, c.StmtMode)
+		default:
+			return d.runPool10(ctx, spec, c.StmtMode)
+		}
+	case "ingest":
+		strategy, batchSize, iterations, err := c.ingestPlan()
+		if err != nil {
+			return Result{}, err
+		}
+		return d.runIngest(ctx, strategy, batchSize, iterations)
+	default:
+		return Result{}, unsupported(d.Name(), c)
+	}
+}
+
+// pgxSetupConn adapts a pgx connection to the shared table setup.
+type pgxSetupConn struct {
+	conn *pgx.Conn
+}
+
+func (s pgxSetupConn) exec(ctx context.Context, sql string) error {
+	_, err := s.conn.Exec(ctx, sql)
+	return err
+}
+
+func (s pgxSetupConn) queryInt(ctx context.Context, sql string) (int, error) {
+	var value int
+	err := s.conn.QueryRow(ctx, sql).Scan(&value)
+	return value, err
+}
+
+func prepareTemplates(p *pgconn.Pipeline, templates map[string]string, orderedNames []string) error {
+	for _, name := range orderedNames {
+		p.SendPrepare(name, templates[name], nil)
+	}
+	if err := p.Sync(); err != nil {
+		return err
+	}
+	expected := len(orderedNames)
+	seen := 0
+	for {
+		results, err := p.GetResults()
+		if err != nil {
+			return err
+		}
+		switch r := results.(type) {
+		case *pgconn.StatementDescription:
+			_ = r
+			seen++
+		case *pgconn.PipelineSync:
+			if seen != expected {
+				return fmt.Errorf("prepare count mismatch: got %d want %d", seen, expected)
+			}
+			return nil
+		case nil:
+			continue
+		default:
+			return fmt.Errorf("unexpected prepare result type %T", r)
+		}
+	}
+}
+
+func consumeResultReader(rr *pgconn.ResultReader, mode resultMode) (batchStats, error) {
+	stats := batchStats{}
+
+	for rr.NextRow() {
+		consumeValues(mode, rr.Values(), &stats)
+	}
+
+	_, err := rr.Close()
+	if err != nil {
+		return batchStats{}, err
+	}
+	stats.completed = 1
+	return stats, nil
+}
+
+func runPipelineOnce(p *pgconn.Pipeline, calls []preparedCall, mode resultMode) (batchStats, error) {
+	for _, call := range calls {
+		p.SendQueryPrepared(call.stmt, call.params, nil, nil)
+	}
+	if err := p.Sync(); err != nil {
+		return batchStats{}, err
+	}
+
+	expected := len(calls)
+	stats := batchStats{}
+
+	for {
+		results, err := p.GetResults()
+		if err != nil {
+			return batchStats{}, err
+		}
+
+		switch r := results.(type) {
+		case *pgconn.ResultReader:
+			readerStats, err := consumeResultReader(r, mode)
+			if err != nil {
+				return batchStats{}, err
+			}
+			stats.add(readerStats)
+		case *pgconn.PipelineSync:
+			if stats.completed != expected {
+				return batchStats{}, fmt.Errorf("completed mismatch: got %d want %d", stats.completed, expected)
+			}
+			return stats, nil
+		case nil:
+			continue
+		default:
+			return batchStats{}, fmt.Errorf("unexpected result type %T", r)
+		}
+	}
+}
+
+func runPipelineOnceUnprepared(p *pgconn.Pipeline, sql string, params [][][]byte, mode resultMode) (batchStats, error) {
+	for _, paramSet := range params {
+		p.SendQueryParams(sql, paramSet, nil, nil, nil)
+	}
+	if err := p.Sync(); err != nil {
+		return batchStats{}, err
+	}
+
+	expected := len(params)
+	stats := batchStats{}
+
+	for {
+		results, err := p.GetResults()
+		if err != nil {
+			return batchStats{}, err
+		}
+
+		switch r := results.(type) {
+		case *pgconn.ResultReader:
+			readerStats, err := consumeResultReader(r, mode)
+			if err != nil {
+				return batchStats{}, err
+			}
+			stats.add(readerStats)
+		case *pgconn.PipelineSync:
+			if stats.completed != expected {
+				return batchStats{}, fmt.Errorf("completed mismatch: got %d want %d", stats.completed, expected)
+			}
+			return stats, nil
+		case nil:
+			continue
+		default:
+			return batchStats{}, fmt.Errorf("unexpected result type %T", r)
+		}
+	}
+}
+
+func (d Pgx) runPreparedPipeline(
+	ctx context.Context,
+	calls []preparedCall,
+	templates map[string]string,
+	orderedNames []string,
+	mode resultMode,
+	iterations int,
+	spec *modeWorkload,
+) (Result, error) {
+	conn, err := pgx.Connect(ctx, d.ConnString)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close(ctx)
+	if spec != nil {
+		if err := ensureWorkloadTables(ctx, pgxSetupConn{conn}, *spec); err != nil {
+			return Result{}, err
+		}
+	}
+
+	p := conn.PgConn().StartPipeline(ctx)
+	defer p.Close()
+
+	if err := prepareTemplates(p, templates, orderedNames); err != nil {
+		return Result{}, err
+	}
+
+	warmup, err := runPipelineOnce(p, calls, mode)
+	if err != nil {
+		return Result{}, err
+	}
+	if warmup.completed != len(calls) {
+		return Result{}, fmt.Errorf("warmup completed %d queries, expected %d", warmup.completed, len(calls))
+	}
+
+	memBefore := readMemStats()
+	total := time.Duration(0)
+	aggregate := batchStats{}
+	for i := 0; i < iterations; i++ {
+		start := time.Now()
+		stats, err := runPipelineOnce(p, calls, mode)
+		if err != nil {
+			return Result{}, err
+		}
+		total += time.Since(start)
+		if stats.completed != len(calls) {
+			return Result{}, fmt.Errorf("run completed %d queries, expected %d", stats.completed, len(calls))
+		}
+		aggregate.add(stats)
+	}
+
+	memAfter := readMemStats()
+
+	result := makeResult(aggregate, total)
+	result.Mem = memStatsBetween(&memBefore, &memAfter, aggregate.completed, "op")
+	return result, nil
+}
+
+func (d Pgx) runUnpreparedPipeline(ctx context.Context, spec modeWorkload) (Result, error) {
+	conn, err := pgx.Connect(ctx, d.ConnString)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close(ctx)
+	if err := ensureWorkloadTables(ctx, pgxSetupConn{conn}, spec); err != nil {
+		return Result{}, err
+	}
+
+	p := conn.PgConn().StartPipeline(ctx)
+	defer p.Close()
+
+	params := buildModeParamBatch(spec)
+	warmup, err := runPipelineOnceUnprepared(p, spec.sql, params, spec.mode)
+	if err != nil {
+		return Result{}, err
+	}
+	if warmup.completed != len(params) {
+		return Result{}, fmt.Errorf("warmup completed %d queries, expected %d", warmup.completed, len(params))
+	}
+
+	memBefore := readMemStats()
+	total := time.Duration(0)
+	aggregate := batchStats{}
+	for i := 0; i < spec.iterations; i++ {
+		start := time.Now()
+		stats, err := runPipelineOnceUnprepared(p, spec.sql, params, spec.mode)
+		if err != nil {
+			return Result{}, err
+		}
+		total += time.Since(start)
+		if stats.completed != len(params) {
+			return Result{}, fmt.Errorf("run completed %d queries, expected %d", stats.completed, len(params))
+		}
+		aggregate.add(stats)
+	}
+
+	memAfter := readMemStats()
+
+	result := makeResult(aggregate, total)
+	result.Mem = memStatsBetween(&memBefore, &memAfter, aggregate.completed, "op")
+	return result, nil
+}
+
+func runSinglePreparedOnce(ctx context.Context, conn *pgconn.PgConn, stmtName string, params [][][]byte, mode resultMode) (batchStats, error) {
+	stats := batchStats{}
+
+	for _, paramSet := range params {
+		rr := conn.ExecPrepared(ctx, stmtName, paramSet, nil, nil)
+		readerStats, err := consumeResultReader(rr, mode)
+		if err != nil {
+			return batchStats{}, err
+		}
+		stats.add(readerStats)
+	}
+
+	return stats, nil
+}
+
+func runSingleUnpreparedOnce(ctx context.Context, conn *pgconn.PgConn, sql string, params [][][]byte, mode resultMode) (batchStats, error) {
+	stats := batchStats{}
+
+	for _, paramSet := range params {
+		rr := conn.ExecParams(ctx, sql, paramSet, nil, nil, nil)
+		readerStats, err := consumeResultReader(rr, mode)
+		if err != nil {
+			return batchStats{}, err
+		}
+		stats.add(readerStats)
+	}
+
+	return stats, nil
+}
+
+// runSingleBatch runs params one round trip at a time on conn, through the
+// prepared stmtName or as unnamed statements depending on stmtMode.
+func runSingleBatch(ctx context.Context, conn *pgconn.PgConn, stmtMode StatementMode, stmtName string, spec modeWorkload, params [][][]byte) (batchStats, error) {
+	switch stmtMode {
+	case StatementModePrepared:
+		return runSinglePreparedOnce(ctx, conn, stmtName, params, spec.mode)
+	case StatementModeUnprepared:
+		return runSingleUnpreparedOnce(ctx, conn, spec.sql, params, spec.mode)
+	default:
+		return batchStats{}, fmt.Errorf("unsupported statement mode %v", stmtMode)
+	}
+}
+
+func (d Pgx) runSingle(ctx context.Context, spec modeWorkload, stmtMode StatementMode) (Result, error) {
+	conn, err := pgx.Connect(ctx, d.ConnString)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close(ctx)
+	if err := ensureWorkloadTables(ctx, pgxSetupConn{conn}, spec); err != nil {
+		return Result{}, err
+	}
+
+	pgConn := conn.PgConn()
+	params := buildModeParamBatch(spec)
+	if stmtMode == StatementModePrepared {
+		if _, err := pgConn.Prepare(ctx, "single_stmt", spec.sql, nil); err != nil {
+			return Result{}, err
+		}
+	}
+
+	warmup, err := runSingleBatch(ctx, pgConn, stmtMode, "single_stmt", spec, params)
+	if err != nil {
+		return Result{}, err
+	}
+	if warmup.completed != len(params) {
+		return Result{}, fmt.Errorf("warmup completed %d queries, expected %d", warmup.completed, len(params))
+	}
+
+	memBefore := readMemStats()
+	total := time.Duration(0)
+	aggregate := batchStats{}
+	for i := 0; i < spec.iterations; i++ {
+		start := time.Now()
+		stats, err := runSingleBatch(ctx, pgConn, stmtMode, "single_stmt", spec, params)
+		if err != nil {
+			return Result{}, err
+		}
+		total += time.Since(start)
+		if stats.completed != len(params) {
+			return Result{}, fmt.Errorf("run completed %d queries, expected %d", stats.completed, len(params))
+		}
+		aggregate.add(stats)
+	}
+
+	memAfter := readMemStats()
+
+	result := makeResult(aggregate, total)
+	result.Mem = memStatsBetween(&memBefore, &memAfter, aggregate.completed, "op")
+	return result, nil
+}
+
+func (d Pgx) runPipeline(ctx context.Context, spec modeWorkload, stmtMode StatementMode) (Result, error) {
+	switch stmtMode {
+	case StatementModePrepared:
+		calls, templates, ordered := buildModeCalls(spec)
+		return d.runPreparedPipeline(ctx, calls, templates, ordered, spec.mode, spec.iterations, &spec)
+	case StatementModeUnprepared:
+		return d.runUnpreparedPipeline(ctx, spec)
+	default:
+		return Result{}, fmt.Errorf("unsupported statement mode %v", stmtMode)
+	}
+}
+
+func (d Pgx) runPool10(ctx context.Context, spec modeWorkload, stmtMode StatementMode) (Result, error) {
+	cfg, err := pgxpool.ParseConfig(d.ConnString)
+	if err != nil {
+		return Result{}, err
+	}
+	cfg.MaxConns = poolSize
+	cfg.MinConns = poolSize
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return Result{}, err
+	}
+	defer pool.Close()
+	if err := pool.AcquireFunc(ctx, func(conn *pgxpool.Conn) error {
+		return ensureWorkloadTables(ctx, pgxSetupConn{conn.Conn()}, spec)
+	}); err != nil {
+		return Result{}, err
+	}
+
+	params := buildModeParamBatch(spec)
+	if len(params)%poolSize != 0 {
+		return Result{}, fmt.Errorf("workload %q produced %d params, not divisible by pool size %d", spec.name, len(params), poolSize)
+	}
+
+	perWorker := len(params) / poolSize
+	workerParams := make([][][][]byte, poolSize)
+	for w := 0; w < poolSize; w++ {
+		startIdx := w * perWorker
+		workerParams[w] = params[startIdx : startIdx+perWorker]
+	}
+
+	startSignal := make(chan struct{})
+	readyCh := make(chan struct{}, poolSize)
+	statsCh := make(chan batchStats, poolSize)
+	errCh := make(chan error, poolSize)
+
+	var wg sync.WaitGroup
+	for w := 0; w < poolSize; w++ {
+		params := workerParams[w]
+		wg.Add(1)
+		go func(idx int, vals [][][]byte) {
+			defer wg.Done()
+
+			poolConn, err := pool.Acquire(ctx)
+			if err != nil {
+				readyCh <- struct{}{}
+				errCh <- err
+				return
+			}
+			defer poolConn.Release()
+
+			pgConn := poolConn.Conn().PgConn()
+			stmtName := fmt.Sprintf("pool_stmt_%d", idx)
+			if stmtMode == StatementModePrepared {
+				if _, err := pgConn.Prepare(ctx, stmtName, spec.sql, nil); err != nil {
+					readyCh <- struct{}{}
+					errCh <- err
+					return
+				}
+			}
+
+			warmup, err := runSingleBatch(ctx, pgConn, stmtMode, stmtName, spec, vals)
+			if err != nil {
+				readyCh <- struct{}{}
+				errCh <- err
+				return
+			}
+			if warmup.completed != len(vals) {
+				readyCh <- struct{}{}
+				errCh <- fmt.Errorf("worker %d warmup completed %d queries, expected %d", idx, warmup.completed, len(vals))
+				return
+			}
+
+			readyCh <- struct{}{}
+			<-startSignal
+
+			measured := batchStats{}
+			for i := 0; i < spec.iterations; i++ {
+				stats, err := runSingleBatch(ctx, pgConn, stmtMode, stmtName, spec, vals)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				if stats.completed != len(vals) {
+					errCh <- fmt.Errorf("worker %d run completed %d queries, expected %d", idx, stats.completed, len(vals))
+					return
+				}
+				measured.add(stats)
+			}
+
+			statsCh <- measured
+		}(w, params)
+	}
+
+	for i := 0; i < poolSize; i++ {
+		<-readyCh
+	}
+
+	memBefore := readMemStats()
+	start := time.Now()
+	close(startSignal)
+	wg.Wait()
+	elapsed := time.Since(start)
+	memAfter := readMemStats()
+
+	select {
+	case err := <-errCh:
+		return Result{}, err
+	default:
+	}
+
+	close(statsCh)
+	aggregate := batchStats{}
+	for stats := range statsCh {
+		aggregate.add(stats)
+	}
+
+	result := makeResult(aggregate, elapsed)
+	result.Mem = memStatsBetween(&memBefore, &memAfter, aggregate.completed, "op")
+	return result, nil
+}
+
+func (d Pgx) Latency(ctx context.Context, c Case) (LatencyResult, error) {
+	spec, err := c.modeWorkload()
+	if err != nil {
+		return LatencyResult{}, err
+	}
+	conn, err := pgx.Connect(ctx, d.ConnString)
+	if err != nil {
+		return LatencyResult{}, err
+	}
+	defer conn.Close(ctx)
+	if err := ensureWorkloadTables(ctx, pgxSetupConn{conn}, spec); err != nil {
+		return LatencyResult{}, err
+	}
+
+	pgConn := conn.PgConn()
+	params := buildModeParamBatch(spec)
+	if c.StmtMode == StatementModePrepared {
+		if _, err := pgConn.Prepare(ctx, "latency_stmt", spec.sql, nil); err != nil {
+			return LatencyResult{}, err
+		}
+	}
+
+	warmupCount := spec.latencySamples
+	if warmupCount > 20 {
+		warmupCount = 20
+	}
+	for i := 0; i < warmupCount; i++ {
+		paramSet := params[i%len(params)]
+		if _, err := runSingleBatch(ctx, pgConn, c.StmtMode, "latency_stmt", spec, [][][]byte{paramSet}); err != nil {
+			return LatencyResult{}, err
+		}
+	}
+
+	samples := make([]time.Duration, 0, spec.latencySamples)
+	total := time.Duration(0)
+	memBefore := readMemStats()
+	for i := 0; i < spec.latencySamples; i++ {
+		paramSet := params[i%len(params)]
+		start := time.Now()
+		stats, err := runSingleBatch(ctx, pgConn, c.StmtMode, "latency_stmt", spec, [][][]byte{paramSet})
+		elapsed := time.Since(start)
+		if err != nil {
+			return LatencyResult{}, err
+		}
+		if stats.completed != 1 {
+			return LatencyResult{}, fmt.Errorf("latency sample completed %d queries, expected 1", stats.completed)
+		}
+		total += elapsed
+		samples = append(samples, elapsed)
+	}
+	memAfter := readMemStats()
+
+	result := latencyFromSamples(samples, total)
+	result.Mem = memStatsBetween(&memBefore, &memAfter, len(samples), "op")
+	return result, nil
+}
+
+func runIngestMultiValuesOnce(ctx context.Context, conn *pgconn.PgConn, chunkParams [][][]byte, rowsPerStmt int) (batchStats, error) {
+	stats := batchStats{}
+
+	for _, params := range chunkParams {
+		stmtName := "ingest_multi_stmt"
+		if len(params) != rowsPerStmt*ingestColumnCount {
+			stmtName = "ingest_multi_tail_stmt"
+		}
+		tag, err := conn.ExecPrepared(ctx, stmtName, params, nil, nil).Close()
+		if err != nil {
+			return batchStats{}, err
+		}
+		stats.completed++
+		stats.rows += int(tag.RowsAffected())
+	}
+
+	return stats, nil
+}
+
+func runIngestPipelineChunk(p *pgconn.Pipeline, rows [][][]byte) (batchStats, error) {
+	for _, row := range rows {
+		p.SendQueryPrepared("ingest_row_stmt", row, nil, nil)
+	}
+	if err := p.Sync(); err != nil {
+		return batchStats{}, err
+	}
+
+	stats := batchStats{}
+	for {
+		results, err := p.GetResults()
+		if err != nil {
+			return batchStats{}, err
+		}
+
+		switch r := results.(type) {
+		case *pgconn.ResultReader:
+			tag, err := r.Close()
+			if err != nil {
+				return batchStats{}, err
+			}
+			stats.completed++
+			stats.rows += int(tag.RowsAffected())
+		case *pgconn.PipelineSync:
+			if stats.completed != len(rows) {
+				return batchStats{}, fmt.Errorf("completed mismatch: got %d want %d", stats.completed, len(rows))
+			}
+			return stats, nil
+		case nil:
+			continue
+		default:
+			return batchStats{}, fmt.Errorf("unexpected result type %T", r)
+		}
+	}
+}
+
+func runIngestPipelinedOnce(ctx context.Context, conn *pgconn.PgConn, rows [][][]byte, depth int) (batchStats, error) {
+	p := conn.StartPipeline(ctx)
+	defer p.Close()
+
+	stats := batchStats{}
+	for start := 0; start < len(rows); start += depth {
+		end := start + depth
+		if end > len(rows) {
+			end = len(rows)
+		}
+		chunkStats, err := runIngestPipelineChunk(p, rows[start:end])
+		if err != nil {
+			return batchStats{}, err
+		}
+		stats.add(chunkStats)
+	}
+
+	return stats, nil
+}
+
+// runIngestCopyOnce loads rows with one COPY per chunk of rowsPerCopy rows.
+func runIngestCopyOnce(ctx context.Context, conn *pgconn.PgConn, rows [][][]byte, rowsPerCopy int) (batchStats, error) {
+	stats := batchStats{}
+	var buf bytes.Buffer
+	for start := 0; start < len(rows); start += rowsPerCopy {
+		end := start + rowsPerCopy
+		if end > len(rows) {
+			end = len(rows)
+		}
+		buf.Reset()
+		for _, row := range rows[start:end] {
+			for idx, value := range row {
+				if idx > 0 {
+					buf.WriteByte('\t')
+				}
+				if value == nil {
+					buf.WriteString(`\N`)
+					continue
+				}
+				buf.Write(value)
+			}
+			buf.WriteByte('\n')
+		}
+
+		tag, err := conn.CopyFrom(ctx, &buf, ingestCopySQL)
+		if err != nil {
+			return batchStats{}, err
+		}
+		stats.completed++
+		stats.rows += int(tag.RowsAffected())
+	}
+	return stats, nil
+}
+
+func (d Pgx) runIngest(ctx context.Context, strategy IngestStrategy, batchSize, iterations int) (Result, error) {
+	conn, err := pgx.Connect(ctx, d.ConnString)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close(ctx)
+	if err := ensureBenchIngest(ctx, pgxSetupConn{conn}); err != nil {
+		return Result{}, err
+	}
+
+	rows := buildIngestRows(ingestTotalRows)
+	payloadBytes := ingestPayloadBytes(rows)
+
+	pgConn := conn.PgConn()
+	var chunkParams [][][]byte
+	switch strategy {
+	case IngestMultiValues:
+		chunkParams = buildIngestChunkParams(rows, batchSize)
+		if _, err := pgConn.Prepare(ctx, "ingest_multi_stmt", buildIngestMultiValuesSQL(batchSize), nil); err != nil {
+			return Result{}, err
+		}
+		if tail := len(rows) % batchSize; tail > 0 {
+			if _, err := pgConn.Prepare(ctx, "ingest_multi_tail_stmt", buildIngestMultiValuesSQL(tail), nil); err != nil {
+				return Result{}, err
+			}
+		}
+	case IngestPipelined:
+		if _, err := pgConn.Prepare(ctx, "ingest_row_stmt", ingestRowSQL, nil); err != nil {
+			return Result{}, err
+		}
+	}
+
+	// Allocation counters only cover the load itself; the TRUNCATE and the row
+	// count check stay outside the measured window.
+	var memDelta runtime.MemStats
+	runOnce := func(mem *runtime.MemStats) (batchStats, time.Duration, error) {
+		if _, err := conn.Exec(ctx, truncateBenchIngestSQL); err != nil {
+			return batchStats{}, 0, err
+		}
+		memBefore := readMemStats()
+		start := time.Now()
+		var stats batchStats
+		var err error
+		switch strategy {
+		case IngestMultiValues:
+			stats, err = runIngestMultiValuesOnce(ctx, pgConn, chunkParams, batchSize)
+		case IngestPipelined:
+			stats, err = runIngestPipelinedOnce(ctx, pgConn, rows, batchSize)
+		case IngestCopy:
+			stats, err = runIngestCopyOnce(ctx, pgConn, rows, batchSize)
+		}
+		elapsed := time.Since(start)
+		if mem != nil {
+			memAfter := readMemStats()
+			addMemDelta(mem, &memBefore, &memAfter)
+		}
+		if err != nil {
+			return batchStats{}, 0, err
+		}
+		if stats.rows != len(rows) {
+			return batchStats{}, 0, fmt.Errorf("%s inserted %d rows, expected %d", strategy, stats.rows, len(rows))
+		}
+		stats.bytes = payloadBytes
+		return stats, elapsed, nil
+	}
+
+	if _, _, err := runOnce(nil); err != nil {
+		return Result{}, err
+	}
+
+	total := time.Duration(0)
+	aggregate := batchStats{}
+	for i := 0; i < iterations; i++ {
+		stats, elapsed, err := runOnce(&memDelta)
+		if err != nil {
+			return Result{}, err
+		}
+		total += elapsed
+		aggregate.add(stats)
+	}
+
+	result := makeResult(aggregate, total)
+	result.HasChecksum = false
+	result.Mem = memStatsBetween(&runtime.MemStats{}, &memDelta, aggregate.rows, "row")
+	return result, nil
+}
+
+// Fault drives a pgxpool workload through a fault proxy and records how the
+// pool rides out the injected failure. Queries go through ExecParams.
+func (d Pgx) Fault(ctx context.Context, c Case) (FaultResult, error) {
+	spec, err := c.modeWorkload()
+	if err != nil {
+		return FaultResult{}, err
+	}
+	cfg, err := pgxpool.ParseConfig(d.ConnString)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	upstreamHost, upstreamPort := cfg.ConnConfig.Host, cfg.ConnConfig.Port
+	if strings.HasPrefix(upstreamHost, "/") {
+		return FaultResult{}, fmt.Errorf("fault mode needs a TCP host, got unix socket %q", upstreamHost)
+	}
+
+	proxy, err := NewFaultProxy("127.0.0.1:0", net.JoinHostPort(upstreamHost, strconv.Itoa(int(upstreamPort))))
+	if err != nil {
+		return FaultResult{}, err
+	}
+	defer proxy.Close()
+
+	proxyHost, proxyPort := proxy.Addr().IP.String(), uint16(proxy.Addr().Port)
+	cfg.ConnConfig.Host = proxyHost
+	cfg.ConnConfig.Port = proxyPort
+	// Fallbacks carry the sslmode=prefer/allow retries, so keep the ones aimed
+	// at the proxied server and route them through the proxy too. Fallbacks for
+	// other hosts would bypass the proxy and are dropped.
+	fallbacks := cfg.ConnConfig.Fallbacks[:0]
+	for _, fallback := range cfg.ConnConfig.Fallbacks {
+		if fallback.Host != upstreamHost || fallback.Port != upstreamPort {
+			continue
+		}
+		fallback.Host = proxyHost
+		fallback.Port = proxyPort
+		fallbacks = append(fallbacks, fallback)
+	}
+	cfg.ConnConfig.Fallbacks = fallbacks
+	cfg.MaxConns = poolSize
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	defer pool.Close()
+
+	if err := pool.AcquireFunc(ctx, func(conn *pgxpool.Conn) error {
+		return ensureWorkloadTables(ctx, pgxSetupConn{conn.Conn()}, spec)
+	}); err != nil {
+		return FaultResult{}, err
+	}
+
+	params := buildModeParamBatch(spec)
+	return runFaultWorkload(ctx, proxy, c.Fault, func(queryCtx context.Context, i int) error {
+		poolConn, err := pool.Acquire(queryCtx)
+		if err != nil {
+			return err
+		}
+		defer poolConn.Release()
+		rr := poolConn.Conn().PgConn().ExecParams(queryCtx, spec.sql, params[i%len(params)], nil, nil, nil)
+		_, err = consumeResultReader(rr, spec.mode)
+		return err
+	}), nil
+}
@@ -0,0 +1,607 @@
+package qailbench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Pq runs cases through lib/pq behind database/sql. database/sql has no
+// pipelining, so the once, pipeline, and pipelined ingest cases report
+// ErrUnsupported.
+type Pq struct {
+	ConnString string
+}
+
+func (d Pq) Name() string {
+	return "pq"
+}
+
+func (d Pq) Run(ctx context.Context, c Case) (Result, error) {
+	switch c.Mode {
+	case "single", "pool10":
+		spec, err := c.modeWorkload()
+		if err != nil {
+			return Result{}, err
+		}
+		if c.Mode == "single" {
+			return d.runSingle(ctx, spec, c.StmtMode)
+		}
+		return d.runPool10(ctx, spec, c.StmtMode)
+	case "ingest":
+		strategy, batchSize, iterations, err := c.ingestPlan()
+		if err != nil {
+			return Result{}, err
+		}
+		if strategy == IngestPipelined {
+			return Result{}, unsupported(d.Name(), c)
+		}
+		return d.runIngest(ctx, strategy, batchSize, iterations)
+	default:
+		return Result{}, unsupported(d.Name(), c)
+	}
+}
+
+// sqlSetupConn adapts a database/sql connection to the shared table setup.
+type sqlSetupConn struct {
+	conn *sql.Conn
+}
+
+func (s sqlSetupConn) exec(ctx context.Context, query string) error {
+	_, err := s.conn.ExecContext(ctx, query)
+	return err
+}
+
+func (s sqlSetupConn) queryInt(ctx context.Context, query string) (int, error) {
+	var value int
+	err := s.conn.QueryRowContext(ctx, query).Scan(&value)
+	return value, err
+}
+
+// textArgs converts text-format parameters to database/sql arguments. lib/pq
+// would send []byte values as bytea, so they are passed as strings.
+func textArgs(params [][]byte) []any {
+	args := make([]any, len(params))
+	for idx, value := range params {
+		if value != nil {
+			args[idx] = string(value)
+		}
+	}
+	return args
+}
+
+func textArgBatch(params [][][]byte) [][]any {
+	batch := make([][]any, len(params))
+	for idx, paramSet := range params {
+		batch[idx] = textArgs(paramSet)
+	}
+	return batch
+}
+
+func consumeRows(rows *sql.Rows, mode resultMode) (batchStats, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return batchStats{}, err
+	}
+	raw := make([]sql.RawBytes, len(columns))
+	dest := make([]any, len(columns))
+	for idx := range raw {
+		dest[idx] = &raw[idx]
+	}
+	values := make([][]byte, len(columns))
+
+	stats := batchStats{}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return batchStats{}, err
+		}
+		for idx, value := range raw {
+			values[idx] = value
+		}
+		consumeValues(mode, values, &stats)
+	}
+	if err := rows.Err(); err != nil {
+		return batchStats{}, err
+	}
+	stats.completed = 1
+	return stats, nil
+}
+
+// pqStatement runs the workload query on one connection, through a prepared
+// statement or as a one-off query depending on the statement mode.
+type pqStatement struct {
+	conn *sql.Conn
+	stmt *sql.Stmt
+	spec modeWorkload
+}
+
+func preparePqStatement(ctx context.Context, conn *sql.Conn, spec modeWorkload, stmtMode StatementMode) (*pqStatement, error) {
+	s := &pqStatement{conn: conn, spec: spec}
+	switch stmtMode {
+	case StatementModePrepared:
+		stmt, err := conn.PrepareContext(ctx, spec.sql)
+		if err != nil {
+			return nil, err
+		}
+		s.stmt = stmt
+	case StatementModeUnprepared:
+	default:
+		return nil, fmt.Errorf("unsupported statement mode %v", stmtMode)
+	}
+	return s, nil
+}
+
+func (s *pqStatement) run(ctx context.Context, args [][]any) (batchStats, error) {
+	stats := batchStats{}
+	for _, argSet := range args {
+		var rows *sql.Rows
+		var err error
+		if s.stmt != nil {
+			rows, err = s.stmt.QueryContext(ctx, argSet...)
+		} else {
+			rows, err = s.conn.QueryContext(ctx, s.spec.sql, argSet...)
+		}
+		if err != nil {
+			return batchStats{}, err
+		}
+		queryStats, err := consumeRows(rows, s.spec.mode)
+		if err != nil {
+			return batchStats{}, err
+		}
+		stats.add(queryStats)
+	}
+	return stats, nil
+}
+
+func (s *pqStatement) close() {
+	if s.stmt != nil {
+		s.stmt.Close()
+	}
+}
+
+// openConn opens a single-connection pool and pins its connection.
+func (d Pq) openConn(ctx context.Context) (*sql.DB, *sql.Conn, error) {
+	db, err := sql.Open("postgres", d.ConnString)
+	if err != nil {
+		return nil, nil, err
+	}
+	db.SetMaxOpenConns(1)
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, conn, nil
+}
+
+func (d Pq) runSingle(ctx context.Context, spec modeWorkload, stmtMode StatementMode) (Result, error) {
+	db, conn, err := d.openConn(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer db.Close()
+	defer conn.Close()
+	if err := ensureWorkloadTables(ctx, sqlSetupConn{conn}, spec); err != nil {
+		return Result{}, err
+	}
+
+	stmt, err := preparePqStatement(ctx, conn, spec, stmtMode)
+	if err != nil {
+		return Result{}, err
+	}
+	defer stmt.close()
+	args := textArgBatch(buildModeParamBatch(spec))
+
+	warmup, err := stmt.run(ctx, args)
+	if err != nil {
+		return Result{}, err
+	}
+	if warmup.completed != len(args) {
+		return Result{}, fmt.Errorf("warmup completed %d queries, expected %d", warmup.completed, len(args))
+	}
+
+	memBefore := readMemStats()
+	total := time.Duration(0)
+	aggregate := batchStats{}
+	for i := 0; i < spec.iterations; i++ {
+		start := time.Now()
+		stats, err := stmt.run(ctx, args)
+		if err != nil {
+			return Result{}, err
+		}
+		total += time.Since(start)
+		if stats.completed != len(args) {
+			return Result{}, fmt.Errorf("run completed %d queries, expected %d", stats.completed, len(args))
+		}
+		aggregate.add(stats)
+	}
+
+	memAfter := readMemStats()
+
+	result := makeResult(aggregate, total)
+	result.Mem = memStatsBetween(&memBefore, &memAfter, aggregate.completed, "op")
+	return result, nil
+}
+
+func (d Pq) runPool10(ctx context.Context, spec modeWorkload, stmtMode StatementMode) (Result, error) {
+	db, err := sql.Open("postgres", d.ConnString)
+	if err != nil {
+		return Result{}, err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(poolSize)
+	db.SetMaxIdleConns(poolSize)
+
+	setup, err := db.Conn(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	err = ensureWorkloadTables(ctx, sqlSetupConn{setup}, spec)
+	setup.Close()
+	if err != nil {
+		return Result{}, err
+	}
+
+	args := textArgBatch(buildModeParamBatch(spec))
+	if len(args)%poolSize != 0 {
+		return Result{}, fmt.Errorf("workload %q produced %d params, not divisible by pool size %d", spec.name, len(args), poolSize)
+	}
+	perWorker := len(args) / poolSize
+
+	startSignal := make(chan struct{})
+	readyCh := make(chan struct{}, poolSize)
+	statsCh := make(chan batchStats, poolSize)
+	errCh := make(chan error, poolSize)
+
+	var wg sync.WaitGroup
+	for w := 0; w < poolSize; w++ {
+		wg.Add(1)
+		go func(idx int, vals [][]any) {
+			defer wg.Done()
+
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				readyCh <- struct{}{}
+				errCh <- err
+				return
+			}
+			defer conn.Close()
+
+			stmt, err := preparePqStatement(ctx, conn, spec, stmtMode)
+			if err != nil {
+				readyCh <- struct{}{}
+				errCh <- err
+				return
+			}
+			defer stmt.close()
+
+			warmup, err := stmt.run(ctx, vals)
+			if err != nil {
+				readyCh <- struct{}{}
+				errCh <- err
+				return
+			}
+			if warmup.completed != len(vals) {
+				readyCh <- struct{}{}
+				errCh <- fmt.Errorf("worker %d warmup completed %d queries, expected %d", idx, warmup.completed, len(vals))
+				return
+			}
+
+			readyCh <- struct{}{}
+			<-startSignal
+
+			measured := batchStats{}
+			for i := 0; i < spec.iterations; i++ {
+				stats, err := stmt.run(ctx, vals)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				if stats.completed != len(vals) {
+					errCh <- fmt.Errorf("worker %d run completed %d queries, expected %d", idx, stats.completed, len(vals))
+					return
+				}
+				measured.add(stats)
+			}
+
+			statsCh <- measured
+		}(w, args[w*perWorker:(w+1)*perWorker])
+	}
+
+	for i := 0; i < poolSize; i++ {
+		<-readyCh
+	}
+
+	memBefore := readMemStats()
+	start := time.Now()
+	close(startSignal)
+	wg.Wait()
+	elapsed := time.Since(start)
+	memAfter := readMemStats()
+
+	select {
+	case err := <-errCh:
+		return Result{}, err
+	default:
+	}
+
+	close(statsCh)
+	aggregate := batchStats{}
+	for stats := range statsCh {
+		aggregate.add(stats)
+	}
+
+	result := makeResult(aggregate, elapsed)
+	result.Mem = memStatsBetween(&memBefore, &memAfter, aggregate.completed, "op")
+	return result, nil
+}
+
+func (d Pq) Latency(ctx context.Context, c Case) (LatencyResult, error) {
+	spec, err := c.modeWorkload()
+	if err != nil {
+		return LatencyResult{}, err
+	}
+	db, conn, err := d.openConn(ctx)
+	if err != nil {
+		return LatencyResult{}, err
+	}
+	defer db.Close()
+	defer conn.Close()
+	if err := ensureWorkloadTables(ctx, sqlSetupConn{conn}, spec); err != nil {
+		return LatencyResult{}, err
+	}
+
+	stmt, err := preparePqStatement(ctx, conn, spec, c.StmtMode)
+	if err != nil {
+		return LatencyResult{}, err
+	}
+	defer stmt.close()
+	args := textArgBatch(buildModeParamBatch(spec))
+
+	warmupCount := spec.latencySamples
+	if warmupCount > 20 {
+		warmupCount = 20
+	}
+	for i := 0; i < warmupCount; i++ {
+		if _, err := stmt.run(ctx, args[i%len(args):i%len(args)+1]); err != nil {
+			return LatencyResult{}, err
+		}
+	}
+
+	samples := make([]time.Duration, 0, spec.latencySamples)
+	total := time.Duration(0)
+	memBefore := readMemStats()
+	for i := 0; i < spec.latencySamples; i++ {
+		argSet := args[i%len(args) : i%len(args)+1]
+		start := time.Now()
+		stats, err := stmt.run(ctx, argSet)
+		elapsed := time.Since(start)
+		if err != nil {
+			return LatencyResult{}, err
+		}
+		if stats.completed != 1 {
+			return LatencyResult{}, fmt.Errorf("latency sample completed %d queries, expected 1", stats.completed)
+		}
+		total += elapsed
+		samples = append(samples, elapsed)
+	}
+	memAfter := readMemStats()
+
+	result := latencyFromSamples(samples, total)
+	result.Mem = memStatsBetween(&memBefore, &memAfter, len(samples), "op")
+	return result, nil
+}
+
+func runPqIngestMultiValuesOnce(ctx context.Context, full, tail *sql.Stmt, chunkArgs [][]any, rowsPerStmt int) (batchStats, error) {
+	stats := batchStats{}
+
+	for _, args := range chunkArgs {
+		stmt := full
+		if len(args) != rowsPerStmt*ingestColumnCount {
+			stmt = tail
+		}
+		res, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return batchStats{}, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return batchStats{}, err
+		}
+		stats.completed++
+		stats.rows += int(affected)
+	}
+
+	return stats, nil
+}
+
+// runPqIngestCopyOnce loads rows with one pq.CopyIn statement per chunk of
+// rowsPerCopy rows, inside a single transaction.
+func runPqIngestCopyOnce(ctx context.Context, conn *sql.Conn, rows [][]any, rowsPerCopy int) (batchStats, error) {
+	txn, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return batchStats{}, err
+	}
+	defer txn.Rollback()
+
+	columns := strings.Split(ingestColumns, ", ")
+	stats := batchStats{}
+	for start := 0; start < len(rows); start += rowsPerCopy {
+		end := start + rowsPerCopy
+		if end > len(rows) {
+			end = len(rows)
+		}
+		stmt, err := txn.PrepareContext(ctx, pq.CopyIn(ingestTable, columns...))
+		if err != nil {
+			return batchStats{}, err
+		}
+		for _, row := range rows[start:end] {
+			if _, err := stmt.ExecContext(ctx, row...); err != nil {
+				stmt.Close()
+				return batchStats{}, err
+			}
+		}
+		res, err := stmt.ExecContext(ctx)
+		stmt.Close()
+		if err != nil {
+			return batchStats{}, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return batchStats{}, err
+		}
+		stats.completed++
+		stats.rows += int(affected)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return batchStats{}, err
+	}
+	return stats, nil
+}
+
+func (d Pq) runIngest(ctx context.Context, strategy IngestStrategy, batchSize, iterations int) (Result, error) {
+	db, conn, err := d.openConn(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer db.Close()
+	defer conn.Close()
+	if err := ensureBenchIngest(ctx, sqlSetupConn{conn}); err != nil {
+		return Result{}, err
+	}
+
+	rows := buildIngestRows(ingestTotalRows)
+	payloadBytes := ingestPayloadBytes(rows)
+	copyRows := textArgBatch(rows)
+
+	var full, tail *sql.Stmt
+	var chunkArgs [][]any
+	if strategy == IngestMultiValues {
+		chunkArgs = textArgBatch(buildIngestChunkParams(rows, batchSize))
+		if full, err = conn.PrepareContext(ctx, buildIngestMultiValuesSQL(batchSize)); err != nil {
+			return Result{}, err
+		}
+		defer full.Close()
+		if tailRows := len(rows) % batchSize; tailRows > 0 {
+			if tail, err = conn.PrepareContext(ctx, buildIngestMultiValuesSQL(tailRows)); err != nil {
+				return Result{}, err
+			}
+			defer tail.Close()
+		}
+	}
+
+	// Allocation counters only cover the load itself; the TRUNCATE stays
+	// outside the measured window.
+	var memDelta runtime.MemStats
+	runOnce := func(mem *runtime.MemStats) (batchStats, time.Duration, error) {
+		if _, err := conn.ExecContext(ctx, truncateBenchIngestSQL); err != nil {
+			return batchStats{}, 0, err
+		}
+		memBefore := readMemStats()
+		start := time.Now()
+		var stats batchStats
+		var err error
+		switch strategy {
+		case IngestMultiValues:
+			stats, err = runPqIngestMultiValuesOnce(ctx, full, tail, chunkArgs, batchSize)
+		case IngestCopy:
+			stats, err = runPqIngestCopyOnce(ctx, conn, copyRows, batchSize)
+		}
+		elapsed := time.Since(start)
+		if mem != nil {
+			memAfter := readMemStats()
+			addMemDelta(mem, &memBefore, &memAfter)
+		}
+		if err != nil {
+			return batchStats{}, 0, err
+		}
+		if stats.rows != len(rows) {
+			return batchStats{}, 0, fmt.Errorf("%s inserted %d rows, expected %d", strategy, stats.rows, len(rows))
+		}
+		stats.bytes = payloadBytes
+		return stats, elapsed, nil
+	}
+
+	if _, _, err := runOnce(nil); err != nil {
+		return Result{}, err
+	}
+
+	total := time.Duration(0)
+	aggregate := batchStats{}
+	for i := 0; i < iterations; i++ {
+		stats, elapsed, err := runOnce(&memDelta)
+		if err != nil {
+			return Result{}, err
+		}
+		total += elapsed
+		aggregate.add(stats)
+	}
+
+	result := makeResult(aggregate, total)
+	result.HasChecksum = false
+	result.Mem = memStatsBetween(&runtime.MemStats{}, &memDelta, aggregate.rows, "row")
+	return result, nil
+}
+
+// Fault drives a database/sql pool through a fault proxy. The proxy address
+// is appended to the connection string, where lib/pq lets later keys win.
+func (d Pq) Fault(ctx context.Context, c Case) (FaultResult, error) {
+	spec, err := c.modeWorkload()
+	if err != nil {
+		return FaultResult{}, err
+	}
+	upstream, err := upstreamAddr(d.ConnString)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	proxy, err := NewFaultProxy("127.0.0.1:0", upstream)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	defer proxy.Close()
+
+	dsn := d.ConnString
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return FaultResult{}, err
+		}
+	}
+	dsn = fmt.Sprintf("%s host=%s port=%d", dsn, proxy.Addr().IP, proxy.Addr().Port)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(poolSize)
+	db.SetMaxIdleConns(poolSize)
+
+	setup, err := db.Conn(ctx)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	err = ensureWorkloadTables(ctx, sqlSetupConn{setup}, spec)
+	setup.Close()
+	if err != nil {
+		return FaultResult{}, err
+	}
+
+	args := textArgBatch(buildModeParamBatch(spec))
+	return runFaultWorkload(ctx, proxy, c.Fault, func(queryCtx context.Context, i int) error {
+		rows, err := db.QueryContext(queryCtx, spec.sql, args[i%len(args)]...)
+		if err != nil {
+			return err
+		}
+		_, err = consumeRows(rows, spec.mode)
+		return err
+	}), nil
+}
@@ -0,0 +1,224 @@
+package qailbench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Qail runs cases through a built qail_pgx_modes_once binary, one process per
+// case, and reads back the JSON record it prints. The binary takes its
+// connection settings from the same environment variables as ConnString.
+type Qail struct {
+	Binary string
+	// ConnString locates the server the fault proxy relays to.
+	ConnString string
+}
+
+func (d Qail) Name() string {
+	return "qail"
+}
+
+func (d Qail) Run(ctx context.Context, c Case) (Result, error) {
+	switch c.Mode {
+	case "single", "pipeline", "pool10", "ingest":
+	default:
+		return Result{}, unsupported(d.Name(), c)
+	}
+
+	record, err := d.runRecord(ctx, c)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{}
+	if result.QPS, err = record.float("qps"); err != nil {
+		return Result{}, err
+	}
+	if result.RowsPerSec, result.HasRows, err = record.optionalFloat("rows_per_sec"); err != nil {
+		return Result{}, err
+	}
+	if result.MiBPerSec, result.HasMiB, err = record.optionalFloat("mib_per_sec"); err != nil {
+		return Result{}, err
+	}
+	if checksum, ok := record["checksum"].(string); ok {
+		value, err := strconv.ParseUint(strings.TrimPrefix(checksum, "0x"), 16, 64)
+		if err != nil {
+			return Result{}, fmt.Errorf("qail: invalid checksum %q", checksum)
+		}
+		result.Checksum = value
+		result.HasChecksum = true
+	}
+	return result, nil
+}
+
+func (d Qail) Latency(ctx context.Context, c Case) (LatencyResult, error) {
+	c.Mode = "latency"
+	record, err := d.runRecord(ctx, c)
+	if err != nil {
+		return LatencyResult{}, err
+	}
+
+	result := LatencyResult{}
+	for _, field := range []struct {
+		column string
+		dst    *float64
+	}{
+		{"avg_ms", &result.AvgMs},
+		{"p50_ms", &result.P50Ms},
+		{"p95_ms", &result.P95Ms},
+		{"p99_ms", &result.P99Ms},
+	} {
+		if *field.dst, err = record.float(field.column); err != nil {
+			return LatencyResult{}, err
+		}
+	}
+	return result, nil
+}
+
+// Fault runs the binary's PgPool through an in-process FaultProxy. The binary
+// connects to the proxy named by QAIL_BENCH_FAULT_PROXY and injects the fault
+// through the control listener named by QAIL_BENCH_FAULT_CONTROL, on the
+// same schedule runFaultWorkload uses.
+func (d Qail) Fault(ctx context.Context, c Case) (FaultResult, error) {
+	c.Mode = "fault"
+	upstream, err := upstreamAddr(d.ConnString)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	proxy, err := NewFaultProxy("127.0.0.1:0", upstream)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	defer proxy.Close()
+	control, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return FaultResult{}, err
+	}
+	defer control.Close()
+	go proxy.ServeControl(control)
+
+	record, err := d.runRecord(ctx, c,
+		"QAIL_BENCH_FAULT_PROXY="+proxy.Addr().String(),
+		"QAIL_BENCH_FAULT_CONTROL="+control.Addr().String(),
+	)
+	if err != nil {
+		return FaultResult{}, err
+	}
+
+	result := FaultResult{}
+	for _, field := range []struct {
+		column string
+		dst    *float64
+	}{
+		{"baseline_qps", &result.BaselineQPS},
+		{"tail_qps", &result.TailQPS},
+		{"last_err_ms", &result.LastErrorMs},
+	} {
+		if *field.dst, err = record.float(field.column); err != nil {
+			return FaultResult{}, err
+		}
+	}
+	for _, field := range []struct {
+		column string
+		dst    *int
+	}{
+		{"total", &result.Total},
+		{"errors", &result.Errors},
+		{"fault_errors", &result.FaultErrors},
+	} {
+		value, err := record.float(field.column)
+		if err != nil {
+			return FaultResult{}, err
+		}
+		*field.dst = int(value)
+	}
+	if result.FirstOKMs, result.Recovered, err = record.optionalFloat("first_ok_ms"); err != nil {
+		return FaultResult{}, err
+	}
+	return result, nil
+}
+
+// args maps a case onto the binary's command line, passing only the flags
+// the mode accepts.
+func (d Qail) args(c Case) []string {
+	args := []string{c.Mode}
+	if c.Workload != "" {
+		args = append(args, "--workload", c.Workload)
+	}
+	switch c.Mode {
+	case "single", "pipeline", "pool10", "latency":
+		args = append(args, "--stmt-mode", c.StmtMode.String())
+	}
+	if c.BatchSize > 0 {
+		args = append(args, "--batch-size", strconv.Itoa(c.BatchSize))
+	}
+	switch c.Mode {
+	case "latency":
+		if c.Samples > 0 {
+			args = append(args, "--samples", strconv.Itoa(c.Samples))
+		}
+	case "fault":
+		args = append(args, "--fault", c.Fault.String())
+	default:
+		if c.Iterations > 0 {
+			args = append(args, "--iterations", strconv.Itoa(c.Iterations))
+		}
+	}
+	return append(args, "--format", "json")
+}
+
+// runRecord runs the binary for c with env added to its environment.
+func (d Qail) runRecord(ctx context.Context, c Case, env ...string) (qailRecord, error) {
+	if c.Mode == "ingest" && c.Workload == "" {
+		return nil, fmt.Errorf("qail: ingest needs a workload")
+	}
+
+	cmd := exec.CommandContext(ctx, d.Binary, d.args(c)...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("qail: %s %s: %w", d.Binary, strings.Join(d.args(c), " "), err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(out), []byte("\n"))
+	if len(lines) != 1 {
+		return nil, fmt.Errorf("qail: expected one JSON record, got %d lines", len(lines))
+	}
+	record := qailRecord{}
+	if err := json.Unmarshal(lines[0], &record); err != nil {
+		return nil, fmt.Errorf("qail: decode record: %w", err)
+	}
+	return record, nil
+}
+
+type qailRecord map[string]any
+
+func (r qailRecord) float(column string) (float64, error) {
+	value, ok, err := r.optionalFloat(column)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("qail: record has no %s", column)
+	}
+	return value, nil
+}
+
+func (r qailRecord) optionalFloat(column string) (float64, bool, error) {
+	switch value := r[column].(type) {
+	case nil:
+		return 0, false, nil
+	case float64:
+		return value, true, nil
+	default:
+		return 0, false, fmt.Errorf("qail: %s is %T, expected a number", column, value)
+	}
+}
@@ -0,0 +1,390 @@
+// Package qailbench is the harness shared by the pgx/qail comparison
+// benchmarks: workload definitions, a Driver interface with pgx, lib/pq, and
+// qail adapters, the fault-injection proxy, and the fixed-column JSON/CSV
+// record format used for regression tracking.
+package qailbench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is wrapped by errors for cases a driver cannot run, such as
+// pipelined modes on lib/pq.
+var ErrUnsupported = errors.New("not supported by this driver")
+
+func unsupported(driver string, c Case) error {
+	return fmt.Errorf("%s cannot run %s/%s: %w", driver, c.Mode, c.Workload, ErrUnsupported)
+}
+
+// Case describes one benchmark run. Zero BatchSize, Iterations, and Samples
+// keep the defaults of the selected workload.
+type Case struct {
+	// Mode is once, single, pipeline, pool10, ingest, latency, or fault.
+	Mode string
+	// Workload names the workload; ingest cases name one IngestStrategy.
+	Workload   string
+	StmtMode   StatementMode
+	Fault      FaultKind
+	BatchSize  int
+	Iterations int
+	Samples    int
+}
+
+// Driver runs benchmark cases against PostgreSQL through one client library.
+type Driver interface {
+	// Name is written to the driver column of every record.
+	Name() string
+	// Run measures throughput for the once, single, pipeline, pool10, and
+	// ingest modes.
+	Run(ctx context.Context, c Case) (Result, error)
+	// Latency measures per-query latency for the latency mode.
+	Latency(ctx context.Context, c Case) (LatencyResult, error)
+	// Fault runs the fault mode through a fault-injecting proxy.
+	Fault(ctx context.Context, c Case) (FaultResult, error)
+}
+
+// Result is the outcome of a throughput run. Ingest runs count statements in
+// QPS and report rows and payload bytes per second.
+type Result struct {
+	QPS         float64
+	RowsPerSec  float64
+	MiBPerSec   float64
+	HasRows     bool
+	HasMiB      bool
+	Checksum    uint64
+	HasChecksum bool
+	// Mem is nil when the driver does not run inside this process.
+	Mem *MemStats
+}
+
+// MemStats is the allocation and GC activity of a measured window,
+// normalised per Unit ("op" for queries, "row" for ingest).
+type MemStats struct {
+	Unit string
+	// Units is how many units the per-unit figures are averaged over.
+	Units         int
+	AllocsPerUnit float64
+	BytesPerUnit  float64
+	GCCycles      uint32
+	GCPauseMs     float64
+}
+
+type LatencyResult struct {
+	AvgMs float64
+	P50Ms float64
+	P95Ms float64
+	P99Ms float64
+	// Mem covers the measured samples and is nil for out-of-process drivers.
+	Mem *MemStats
+}
+
+type FaultResult struct {
+	BaselineQPS float64
+	TailQPS     float64
+	Total       int
+	Errors      int
+	FaultErrors int
+	// FirstOKMs is only meaningful when Recovered is set.
+	FirstOKMs      float64
+	LastErrorMs    float64
+	Recovered      bool
+	LastErrMessage string
+}
+
+type StatementMode int
+
+const (
+	StatementModePrepared StatementMode = iota
+	StatementModeUnprepared
+)
+
+func ParseStatementMode(name string) (StatementMode, error) {
+	switch name {
+	case "", "prepared", "prep":
+		return StatementModePrepared, nil
+	case "unprepared", "uncached", "raw":
+		return StatementModeUnprepared, nil
+	default:
+		return StatementModePrepared, fmt.Errorf("unknown statement mode %q (expected prepared or unprepared)", name)
+	}
+}
+
+func (m StatementMode) String() string {
+	switch m {
+	case StatementModePrepared:
+		return "prepared"
+	case StatementModeUnprepared:
+		return "unprepared"
+	default:
+		return "unknown"
+	}
+}
+
+type FaultKind int
+
+const (
+	// FaultReset drops every proxied connection and refuses new ones, as if
+	// the server was killed and is restarting.
+	FaultReset FaultKind = iota
+	// FaultBlackhole keeps connections open but stops forwarding bytes, as if
+	// packets were being dropped on the path.
+	FaultBlackhole
+)
+
+func ParseFaultKind(name string) (FaultKind, error) {
+	switch name {
+	case "", "reset", "restart", "kill":
+		return FaultReset, nil
+	case "blackhole", "drop", "partition":
+		return FaultBlackhole, nil
+	default:
+		return FaultReset, fmt.Errorf("unknown fault %q (expected reset or blackhole)", name)
+	}
+}
+
+func (k FaultKind) String() string {
+	switch k {
+	case FaultReset:
+		return "reset"
+	case FaultBlackhole:
+		return "blackhole"
+	default:
+		return "unknown"
+	}
+}
+
+type IngestStrategy int
+
+const (
+	IngestMultiValues IngestStrategy = iota
+	IngestPipelined
+	IngestCopy
+)
+
+// IngestStrategies lists every strategy in the order ingest runs them.
+var IngestStrategies = []IngestStrategy{IngestMultiValues, IngestPipelined, IngestCopy}
+
+func ParseIngestStrategy(name string) (IngestStrategy, error) {
+	switch name {
+	case "multi_values", "multi", "values":
+		return IngestMultiValues, nil
+	case "pipelined", "pipeline_rows", "single_row":
+		return IngestPipelined, nil
+	case "copy", "copy_from":
+		return IngestCopy, nil
+	default:
+		return IngestMultiValues, fmt.Errorf("unknown ingest workload %q (expected multi_values, pipelined, or copy)", name)
+	}
+}
+
+func (s IngestStrategy) String() string {
+	switch s {
+	case IngestMultiValues:
+		return "multi_values"
+	case IngestPipelined:
+		return "pipelined"
+	case IngestCopy:
+		return "copy"
+	default:
+		return "unknown"
+	}
+}
+
+// StatementMode reports the stmt_mode recorded for the strategy: the INSERT
+// strategies run prepared statements, while COPY has no statement to prepare.
+func (s IngestStrategy) StatementMode() string {
+	if s == IngestCopy {
+		return "copy"
+	}
+	return "prepared"
+}
+
+// IngestBatchSize resolves a requested batch size for strategy: rows per
+// statement for multi_values, rows per pipeline sync for pipelined, and rows
+// per COPY for copy. Zero selects the strategy default.
+func IngestBatchSize(strategy IngestStrategy, requested int) (int, error) {
+	switch strategy {
+	case IngestMultiValues:
+		if requested > ingestMultiValuesMaxRows {
+			return 0, fmt.Errorf("multi_values batch size %d exceeds %d rows (65535 bind parameters)", requested, ingestMultiValuesMaxRows)
+		}
+		if requested == 0 {
+			return ingestMultiValuesRows, nil
+		}
+	case IngestPipelined:
+		if requested == 0 {
+			return ingestPipelineDepth, nil
+		}
+	case IngestCopy:
+		if requested == 0 {
+			return ingestTotalRows, nil
+		}
+	}
+	return requested, nil
+}
+
+func envOverride(primary, fallback, defaultValue string) string {
+	if value := os.Getenv(primary); value != "" {
+		return value
+	}
+	if value := os.Getenv(fallback); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// ConnString builds the benchmark connection string from
+// QAIL_BENCH_DATABASE_URL, DATABASE_URL, or the QAIL_BENCH_*/PG* variables.
+func ConnString() string {
+	if url := os.Getenv("QAIL_BENCH_DATABASE_URL"); url != "" {
+		return url
+	}
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		return url
+	}
+
+	host := envOverride("QAIL_BENCH_HOST", "PGHOST", "127.0.0.1")
+	port := envOverride("QAIL_BENCH_PORT", "PGPORT", "5432")
+	user := envOverride("QAIL_BENCH_USER", "PGUSER", "orion")
+	database := envOverride("QAIL_BENCH_DB", "PGDATABASE", "example_staging")
+	password := envOverride("QAIL_BENCH_PASSWORD", "PGPASSWORD", "")
+	sslmode := envOverride("QAIL_BENCH_SSLMODE", "PGSSLMODE", "disable")
+
+	parts := []string{
+		fmt.Sprintf("host=%s", host),
+		fmt.Sprintf("port=%s", port),
+		fmt.Sprintf("user=%s", user),
+		fmt.Sprintf("dbname=%s", database),
+		fmt.Sprintf("sslmode=%s", sslmode),
+	}
+	if password != "" {
+		parts = append(parts, fmt.Sprintf("password=%s", password))
+	}
+	return strings.Join(parts, " ")
+}
+
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(float64(len(sorted))*p + 0.999999999)
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+type batchStats struct {
+	completed int
+	rows      int
+	bytes     int
+	checksum  uint64
+}
+
+func (s *batchStats) add(other batchStats) {
+	s.completed += other.completed
+	s.rows += other.rows
+	s.bytes += other.bytes
+	s.checksum += other.checksum
+}
+
+func makeResult(stats batchStats, elapsed time.Duration) Result {
+	seconds := elapsed.Seconds()
+	result := Result{
+		QPS:         float64(stats.completed) / seconds,
+		Checksum:    stats.checksum,
+		HasChecksum: true,
+	}
+	if stats.rows > 0 {
+		result.HasRows = true
+		result.RowsPerSec = float64(stats.rows) / seconds
+	}
+	if stats.bytes > 0 {
+		result.HasMiB = true
+		result.MiBPerSec = (float64(stats.bytes) / (1024.0 * 1024.0)) / seconds
+	}
+	return result
+}
+
+func readMemStats() runtime.MemStats {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats
+}
+
+// addMemDelta accumulates the allocation and GC counters that changed between
+// before and after into total, for runs measured in several separate windows.
+func addMemDelta(total, before, after *runtime.MemStats) {
+	total.Mallocs += after.Mallocs - before.Mallocs
+	total.TotalAlloc += after.TotalAlloc - before.TotalAlloc
+	total.NumGC += after.NumGC - before.NumGC
+	total.PauseTotalNs += after.PauseTotalNs - before.PauseTotalNs
+}
+
+// memStatsBetween normalises the allocation and GC activity between two
+// snapshots per unit; it returns nil when nothing was measured.
+func memStatsBetween(before, after *runtime.MemStats, units int, unit string) *MemStats {
+	if units <= 0 {
+		return nil
+	}
+	return &MemStats{
+		Unit:          unit,
+		Units:         units,
+		AllocsPerUnit: float64(after.Mallocs-before.Mallocs) / float64(units),
+		BytesPerUnit:  float64(after.TotalAlloc-before.TotalAlloc) / float64(units),
+		GCCycles:      after.NumGC - before.NumGC,
+		GCPauseMs:     float64(after.PauseTotalNs-before.PauseTotalNs) / 1e6,
+	}
+}
+
+// CombineMemStats merges the memory stats of several runs, weighting the
+// per-unit figures by each run's units. It returns nil unless every run
+// reported stats in the same unit.
+func CombineMemStats(runs []*MemStats) *MemStats {
+	if len(runs) == 0 || runs[0] == nil {
+		return nil
+	}
+	combined := &MemStats{Unit: runs[0].Unit}
+	allocs, bytes := 0.0, 0.0
+	for _, run := range runs {
+		if run == nil || run.Unit != combined.Unit {
+			return nil
+		}
+		combined.Units += run.Units
+		allocs += run.AllocsPerUnit * float64(run.Units)
+		bytes += run.BytesPerUnit * float64(run.Units)
+		combined.GCCycles += run.GCCycles
+		combined.GCPauseMs += run.GCPauseMs
+	}
+	if combined.Units == 0 {
+		return nil
+	}
+	combined.AllocsPerUnit = allocs / float64(combined.Units)
+	combined.BytesPerUnit = bytes / float64(combined.Units)
+	return combined
+}
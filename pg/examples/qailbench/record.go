@@ -0,0 +1,222 @@
+package qailbench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Columns is the fixed column set of every machine-readable record, in
+// output order; qail_pgx_modes_once.rs writes the same columns. A column that
+// does not apply to a record is written as null in JSON and left empty in
+// CSV, so output from different modes, drivers, and runs lines up.
+var Columns = []string{
+	"driver",
+	"mode",
+	"stmt_mode",
+	"workload",
+	"fault",
+	"qps",
+	"median_qps",
+	"p95_qps",
+	"rows_per_sec",
+	"mib_per_sec",
+	"checksum",
+	"p50_ms",
+	"p95_ms",
+	"p99_ms",
+	"avg_ms",
+	"baseline_qps",
+	"tail_qps",
+	"errors",
+	"fault_errors",
+	"total",
+	"first_ok_ms",
+	"last_err_ms",
+	"mem_unit",
+	"allocs_per_unit",
+	"bytes_per_unit",
+	"gc_cycles",
+	"gc_pause_ms",
+}
+
+// Record holds the values of one result row keyed by column name. Columns
+// missing from the map are written as empty.
+type Record map[string]any
+
+func ParseFormat(name string) (string, error) {
+	switch name {
+	case "", "text":
+		return "text", nil
+	case "json", "csv":
+		return name, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (expected text, json, or csv)", name)
+	}
+}
+
+func NewRecord(driver, mode, stmtMode, workload string) Record {
+	return Record{
+		"driver":    driver,
+		"mode":      mode,
+		"stmt_mode": stmtMode,
+		"workload":  workload,
+	}
+}
+
+// SetMem fills the memory columns; a nil mem leaves them empty.
+func (r Record) SetMem(mem *MemStats) {
+	if mem == nil {
+		return
+	}
+	r["mem_unit"] = mem.Unit
+	r["allocs_per_unit"] = mem.AllocsPerUnit
+	r["bytes_per_unit"] = mem.BytesPerUnit
+	r["gc_cycles"] = int(mem.GCCycles)
+	r["gc_pause_ms"] = mem.GCPauseMs
+}
+
+func (r Record) SetResult(result Result) {
+	r["qps"] = result.QPS
+	if result.HasRows {
+		r["rows_per_sec"] = result.RowsPerSec
+	}
+	if result.HasMiB {
+		r["mib_per_sec"] = result.MiBPerSec
+	}
+	if result.HasChecksum {
+		r["checksum"] = fmt.Sprintf("0x%x", result.Checksum)
+	}
+	r.SetMem(result.Mem)
+}
+
+func (r Record) SetLatency(result LatencyResult) {
+	r["p50_ms"] = result.P50Ms
+	r["p95_ms"] = result.P95Ms
+	r["p99_ms"] = result.P99Ms
+	r["avg_ms"] = result.AvgMs
+	r.SetMem(result.Mem)
+}
+
+func (r Record) SetFault(kind FaultKind, result FaultResult) {
+	r["fault"] = kind.String()
+	r["baseline_qps"] = result.BaselineQPS
+	r["tail_qps"] = result.TailQPS
+	r["errors"] = result.Errors
+	r["fault_errors"] = result.FaultErrors
+	r["total"] = result.Total
+	if result.Recovered {
+		r["first_ok_ms"] = result.FirstOKMs
+	}
+	r["last_err_ms"] = result.LastErrorMs
+}
+
+// value returns the value stored for column and whether it should be
+// written; non-finite floats are treated as missing.
+func (r Record) value(column string) (any, bool) {
+	value, ok := r[column]
+	if !ok {
+		return nil, false
+	}
+	if f, isFloat := value.(float64); isFloat && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return nil, false
+	}
+	return value, true
+}
+
+func (r Record) validate() error {
+	known := 0
+	for _, column := range Columns {
+		if _, ok := r[column]; ok {
+			known++
+		}
+	}
+	if known != len(r) {
+		return fmt.Errorf("record has columns outside the result schema: %v", r)
+	}
+	return nil
+}
+
+func formatFieldValue(value any) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', 3, 64)
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// WriteRecords writes records as JSON lines, or as CSV under a header row of
+// Columns. Every record carries every column.
+func WriteRecords(w io.Writer, format string, records []Record) error {
+	for _, record := range records {
+		if err := record.validate(); err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case "json":
+		for _, record := range records {
+			var b strings.Builder
+			b.WriteByte('{')
+			for idx, column := range Columns {
+				if idx > 0 {
+					b.WriteByte(',')
+				}
+				name, err := json.Marshal(column)
+				if err != nil {
+					return err
+				}
+				b.Write(name)
+				b.WriteByte(':')
+				value, ok := record.value(column)
+				if !ok {
+					b.WriteString("null")
+					continue
+				}
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return err
+				}
+				b.Write(encoded)
+			}
+			b.WriteString("}\n")
+			if _, err := io.WriteString(w, b.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(Columns); err != nil {
+			return err
+		}
+		for _, record := range records {
+			row := make([]string, 0, len(Columns))
+			for _, column := range Columns {
+				value, ok := record.value(column)
+				if !ok {
+					row = append(row, "")
+					continue
+				}
+				row = append(row, formatFieldValue(value))
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
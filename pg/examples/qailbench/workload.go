@@ -0,0 +1,703 @@
+package qailbench
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	poolSize = 10
+
+	sqlByID = "SELECT id, name FROM harbors WHERE id = $1"
+
+	payloadRowsSQL = "SELECT id, name, bio, region, visits, active, ratio, optional_note " +
+		"FROM qail_bench_payload " +
+		"WHERE id <= $1::int " +
+		"ORDER BY id"
+
+	manyParamsParamCount = 32
+
+	pointBatchSize       = 10_000
+	pointIterations      = 5
+	wideRowsBatchSize    = 100
+	wideRowsIterations   = 3
+	largeRowsBatchSize   = 20
+	largeRowsIterations  = 2
+	manyParamsBatchSize  = 5_000
+	manyParamsIterations = 5
+	aggregateBatchSize   = 2_000
+	aggregateIterations  = 3
+
+	fnvOffset = uint64(0xcbf29ce484222325)
+	fnvPrime  = uint64(1099511628211)
+
+	benchPayloadTargetRows    = 20_000
+	benchManyParamsTargetRows = 512
+	benchSetupLockSQL         = "SELECT pg_advisory_lock(60119029)"
+	benchSetupUnlockSQL       = "SELECT pg_advisory_unlock(60119029)"
+	createBenchPayloadSQL     = "CREATE TABLE IF NOT EXISTS qail_bench_payload (" +
+		"id INTEGER PRIMARY KEY, " +
+		"name TEXT NOT NULL, " +
+		"bio TEXT NOT NULL, " +
+		"region TEXT NOT NULL, " +
+		"visits INTEGER NOT NULL, " +
+		"active BOOLEAN NOT NULL, " +
+		"ratio NUMERIC(12, 3) NOT NULL, " +
+		"optional_note TEXT NULL" +
+		")"
+	aggregateSQL = "SELECT " +
+		"COALESCE(SUM(visits), 0)::bigint AS sum_visits, " +
+		"COALESCE(MAX(visits), 0)::bigint AS max_visits, " +
+		"COUNT(*)::bigint AS row_count, " +
+		"COALESCE(SUM(CASE WHEN active THEN 1 ELSE 0 END), 0)::bigint AS active_count " +
+		"FROM qail_bench_payload " +
+		"WHERE id <= $1::int"
+
+	ingestTotalRows       = 100_000
+	ingestIterations      = 3
+	ingestMultiValuesRows = 500
+	ingestPipelineDepth   = 10_000
+	ingestTable           = "qail_bench_ingest"
+	ingestColumnCount     = 6
+	ingestColumns         = "id, name, visits, active, ratio, note"
+	createBenchIngestSQL  = "CREATE TABLE IF NOT EXISTS qail_bench_ingest (" +
+		"id INTEGER NOT NULL, " +
+		"name TEXT NOT NULL, " +
+		"visits INTEGER NOT NULL, " +
+		"active BOOLEAN NOT NULL, " +
+		"ratio NUMERIC(12, 3) NOT NULL, " +
+		"note TEXT NULL" +
+		")"
+	truncateBenchIngestSQL = "TRUNCATE qail_bench_ingest"
+	ingestRowSQL           = "INSERT INTO qail_bench_ingest (" + ingestColumns + ") " +
+		"VALUES ($1::int, $2::text, $3::int, $4::bool, $5::numeric, $6::text)"
+	ingestCopySQL = "COPY qail_bench_ingest (" + ingestColumns + ") FROM STDIN"
+	// Bind parameters per statement are capped at 65535 by the protocol.
+	ingestMultiValuesMaxRows = 65535 / ingestColumnCount
+
+	faultLeadTime     = 2 * time.Second
+	faultDuration     = 3 * time.Second
+	faultTailTime     = 5 * time.Second
+	faultQueryTimeout = time.Second
+)
+
+// Defaults of the once mode, which strict repeats over several rounds.
+const (
+	OnceBatchSize  = 10_000
+	OnceIterations = 5
+)
+
+type preparedCall struct {
+	stmt   string
+	params [][]byte
+}
+
+type resultMode int
+
+const (
+	resultModePointRows resultMode = iota
+	resultModeScalarInt
+	resultModeWideRows
+	resultModeAggregateScalars
+)
+
+type modeWorkload struct {
+	name                    string
+	sql                     string
+	batchSize               int
+	iterations              int
+	latencySamples          int
+	mode                    resultMode
+	requiresBenchPayload    bool
+	requiresBenchManyParams bool
+}
+
+func manyParamColumnName(idx int) string {
+	return fmt.Sprintf("p%02d", idx+1)
+}
+
+func buildManyParamsCreateSQL() string {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE IF NOT EXISTS qail_bench_many_params (slot INTEGER PRIMARY KEY, total BIGINT NOT NULL")
+	for idx := 0; idx < manyParamsParamCount; idx++ {
+		b.WriteString(", ")
+		b.WriteString(manyParamColumnName(idx))
+		b.WriteString(" INTEGER NOT NULL")
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func buildManyParamsIndexSQL() string {
+	var b strings.Builder
+	b.WriteString("CREATE UNIQUE INDEX IF NOT EXISTS qail_bench_many_params_lookup_idx ON qail_bench_many_params (")
+	for idx := 0; idx < manyParamsParamCount; idx++ {
+		if idx > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(manyParamColumnName(idx))
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func buildManyParamsInsertSQL(startSlot, endSlot int) string {
+	sumCoeff := 0
+	for idx := 0; idx < manyParamsParamCount; idx++ {
+		sumCoeff += idx + 1
+	}
+
+	var b strings.Builder
+	b.WriteString("INSERT INTO qail_bench_many_params (slot, total")
+	for idx := 0; idx < manyParamsParamCount; idx++ {
+		b.WriteString(", ")
+		b.WriteString(manyParamColumnName(idx))
+	}
+	b.WriteString(") SELECT gs, ")
+	b.WriteString(fmt.Sprintf("(gs * %d)::bigint", sumCoeff))
+	for idx := 0; idx < manyParamsParamCount; idx++ {
+		b.WriteString(fmt.Sprintf(", gs * %d", idx+1))
+	}
+	b.WriteString(fmt.Sprintf(" FROM generate_series(%d, %d) AS gs ON CONFLICT (slot) DO NOTHING", startSlot, endSlot))
+	return b.String()
+}
+
+func buildManyParamsSelectSQL() string {
+	var b strings.Builder
+	b.WriteString("SELECT total FROM qail_bench_many_params WHERE ")
+	for idx := 0; idx < manyParamsParamCount; idx++ {
+		if idx > 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(manyParamColumnName(idx))
+		b.WriteString(fmt.Sprintf(" = $%d::int", idx+1))
+	}
+	b.WriteString(" LIMIT 1")
+	return b.String()
+}
+
+func modeWorkloadFromName(name string) (modeWorkload, error) {
+	switch name {
+	case "point", "lookup":
+		return modeWorkload{
+			name:                    "point",
+			sql:                     sqlByID,
+			batchSize:               pointBatchSize,
+			iterations:              pointIterations,
+			latencySamples:          2000,
+			mode:                    resultModePointRows,
+			requiresBenchPayload:    false,
+			requiresBenchManyParams: false,
+		}, nil
+	case "wide_rows", "wide":
+		return modeWorkload{
+			name:                    "wide_rows",
+			sql:                     payloadRowsSQL,
+			batchSize:               wideRowsBatchSize,
+			iterations:              wideRowsIterations,
+			latencySamples:          120,
+			mode:                    resultModeWideRows,
+			requiresBenchPayload:    true,
+			requiresBenchManyParams: false,
+		}, nil
+	case "large_rows", "large":
+		return modeWorkload{
+			name:                    "large_rows",
+			sql:                     payloadRowsSQL,
+			batchSize:               largeRowsBatchSize,
+			iterations:              largeRowsIterations,
+			latencySamples:          40,
+			mode:                    resultModeWideRows,
+			requiresBenchPayload:    true,
+			requiresBenchManyParams: false,
+		}, nil
+	case "many_params", "params":
+		return modeWorkload{
+			name:                    "many_params",
+			sql:                     buildManyParamsSelectSQL(),
+			batchSize:               manyParamsBatchSize,
+			iterations:              manyParamsIterations,
+			latencySamples:          2000,
+			mode:                    resultModeScalarInt,
+			requiresBenchPayload:    false,
+			requiresBenchManyParams: true,
+		}, nil
+	case "aggregate", "agg", "server_heavy":
+		return modeWorkload{
+			name:                    "aggregate",
+			sql:                     aggregateSQL,
+			batchSize:               aggregateBatchSize,
+			iterations:              aggregateIterations,
+			latencySamples:          40,
+			mode:                    resultModeAggregateScalars,
+			requiresBenchPayload:    true,
+			requiresBenchManyParams: false,
+		}, nil
+	default:
+		return modeWorkload{}, fmt.Errorf("unknown workload %q (expected point, wide_rows, large_rows, many_params, or aggregate)", name)
+	}
+}
+
+// modeWorkload resolves the workload of a single, pipeline, pool10, latency,
+// or fault case, applying its batch size, iteration, and sample overrides.
+func (c Case) modeWorkload() (modeWorkload, error) {
+	name := c.Workload
+	if name == "" {
+		name = "point"
+	}
+	spec, err := modeWorkloadFromName(name)
+	if err != nil {
+		return modeWorkload{}, err
+	}
+	if c.BatchSize > 0 {
+		spec.batchSize = c.BatchSize
+	}
+	if c.Iterations > 0 {
+		spec.iterations = c.Iterations
+	}
+	if c.Samples > 0 {
+		spec.latencySamples = c.Samples
+	}
+	return spec, nil
+}
+
+// iterationsOr returns the case's iteration override, or fallback if unset.
+func (c Case) iterationsOr(fallback int) int {
+	if c.Iterations > 0 {
+		return c.Iterations
+	}
+	return fallback
+}
+
+// ingestPlan resolves the strategy, rows per batch, and iterations of an
+// ingest case.
+func (c Case) ingestPlan() (IngestStrategy, int, int, error) {
+	strategy, err := ParseIngestStrategy(c.Workload)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	batchSize, err := IngestBatchSize(strategy, c.BatchSize)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return strategy, batchSize, c.iterationsOr(ingestIterations), nil
+}
+
+func buildModeParamBatch(spec modeWorkload) [][][]byte {
+	switch spec.name {
+	case "point":
+		params := make([][][]byte, 0, spec.batchSize)
+		for i := 1; i <= spec.batchSize; i++ {
+			id := (i % 10_000) + 1
+			params = append(params, [][]byte{[]byte(strconv.Itoa(id))})
+		}
+		return params
+	case "wide_rows":
+		rowCounts := []string{"128", "256", "384", "512"}
+		params := make([][][]byte, 0, spec.batchSize)
+		for i := 0; i < spec.batchSize; i++ {
+			params = append(params, [][]byte{[]byte(rowCounts[i%len(rowCounts)])})
+		}
+		return params
+	case "many_params":
+		params := make([][][]byte, 0, spec.batchSize)
+		for queryIdx := 0; queryIdx < spec.batchSize; queryIdx++ {
+			rowSlot := (queryIdx % benchManyParamsTargetRows) + 1
+			row := make([][]byte, manyParamsParamCount)
+			for paramIdx := 0; paramIdx < manyParamsParamCount; paramIdx++ {
+				row[paramIdx] = []byte(strconv.Itoa(rowSlot * (paramIdx + 1)))
+			}
+			params = append(params, row)
+		}
+		return params
+	case "large_rows":
+		rowCounts := []string{"10000", "12000", "14000", "16000"}
+		params := make([][][]byte, 0, spec.batchSize)
+		for i := 0; i < spec.batchSize; i++ {
+			params = append(params, [][]byte{[]byte(rowCounts[i%len(rowCounts)])})
+		}
+		return params
+	case "aggregate":
+		rowCounts := []string{"8000", "12000", "16000", "20000"}
+		params := make([][][]byte, 0, spec.batchSize)
+		for i := 0; i < spec.batchSize; i++ {
+			params = append(params, [][]byte{[]byte(rowCounts[i%len(rowCounts)])})
+		}
+		return params
+	default:
+		return nil
+	}
+}
+
+func buildModeCalls(spec modeWorkload) ([]preparedCall, map[string]string, []string) {
+	params := buildModeParamBatch(spec)
+	calls := make([]preparedCall, 0, len(params))
+	for _, paramSet := range params {
+		calls = append(calls, preparedCall{
+			stmt:   "mode_stmt",
+			params: paramSet,
+		})
+	}
+
+	return calls, map[string]string{"mode_stmt": spec.sql}, []string{"mode_stmt"}
+}
+
+func buildLiteralWorkload(batchSize int) ([]preparedCall, map[string]string, []string) {
+	templates := map[string]string{}
+	ordered := make([]string, 0, 10)
+	calls := make([]preparedCall, 0, batchSize)
+
+	for i := 1; i <= batchSize; i++ {
+		limit := (i % 10) + 1
+		name := fmt.Sprintf("lit_%d", limit)
+		if _, ok := templates[name]; !ok {
+			templates[name] = fmt.Sprintf("SELECT id, name FROM harbors LIMIT %d", limit)
+			ordered = append(ordered, name)
+		}
+		calls = append(calls, preparedCall{stmt: name, params: nil})
+	}
+
+	return calls, templates, ordered
+}
+
+func buildParameterizedWorkload(batchSize int) ([]preparedCall, map[string]string, []string) {
+	templates := map[string]string{
+		"param_id": "SELECT id, name FROM harbors WHERE id = $1",
+	}
+	ordered := []string{"param_id"}
+	calls := make([]preparedCall, 0, batchSize)
+
+	for i := 1; i <= batchSize; i++ {
+		id := (i % 10_000) + 1
+		calls = append(calls, preparedCall{
+			stmt:   "param_id",
+			params: [][]byte{[]byte(strconv.Itoa(id))},
+		})
+	}
+
+	return calls, templates, ordered
+}
+
+// OnceWorkloadTitle describes a once/strict workload for text output.
+func OnceWorkloadTitle(name string) (string, error) {
+	switch name {
+	case "literal":
+		return "Workload A: template-cached literal LIMIT (0 bind params)", nil
+	case "param", "parameterized":
+		return "Workload B: template-cached parameterized filter (1 bind param)", nil
+	default:
+		return "", fmt.Errorf("unknown workload %q (expected literal or param)", name)
+	}
+}
+
+// onceWorkload builds the calls of a once case: a pipeline of prepared
+// template calls over the literal or param workload.
+func (c Case) onceWorkload() ([]preparedCall, map[string]string, []string, error) {
+	batchSize := OnceBatchSize
+	if c.BatchSize > 0 {
+		batchSize = c.BatchSize
+	}
+	switch c.Workload {
+	case "", "literal":
+		calls, templates, ordered := buildLiteralWorkload(batchSize)
+		return calls, templates, ordered, nil
+	case "param", "parameterized":
+		calls, templates, ordered := buildParameterizedWorkload(batchSize)
+		return calls, templates, ordered, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown workload %q (expected literal or param)", c.Workload)
+	}
+}
+
+// setupConn is the part of a client connection the table setup needs, so
+// every driver shares the same setup SQL.
+type setupConn interface {
+	exec(ctx context.Context, sql string) error
+	queryInt(ctx context.Context, sql string) (int, error)
+}
+
+// withSetupLock runs fn under the advisory lock that serialises table setup
+// across concurrently starting benchmark processes.
+func withSetupLock(ctx context.Context, conn setupConn, fn func() error) error {
+	if err := conn.exec(ctx, benchSetupLockSQL); err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.exec(ctx, benchSetupUnlockSQL)
+	}()
+	return fn()
+}
+
+func ensureBenchPayload(ctx context.Context, conn setupConn) error {
+	return withSetupLock(ctx, conn, func() error {
+		if err := conn.exec(ctx, createBenchPayloadSQL); err != nil {
+			return err
+		}
+
+		currentRows, err := conn.queryInt(ctx, "SELECT COALESCE(MAX(id), 0) FROM qail_bench_payload")
+		if err != nil {
+			return err
+		}
+		if currentRows < benchPayloadTargetRows {
+			insertSQL := fmt.Sprintf(
+				"INSERT INTO qail_bench_payload "+
+					"(id, name, bio, region, visits, active, ratio, optional_note) "+
+					"SELECT gs, "+
+					"       ('harbor-' || gs)::text, "+
+					"       repeat(md5(gs::text), 4), "+
+					"       repeat(md5((gs * 17)::text), 3), "+
+					"       (gs * 11), "+
+					"       (gs %% 2 = 0), "+
+					"       round((gs::numeric / 7.0), 3), "+
+					"       CASE WHEN gs %% 5 = 0 THEN NULL ELSE repeat(md5((gs * 3)::text), 2) END "+
+					"FROM generate_series(%d, %d) AS gs "+
+					"ON CONFLICT (id) DO NOTHING",
+				currentRows+1,
+				benchPayloadTargetRows,
+			)
+			if err := conn.exec(ctx, insertSQL); err != nil {
+				return err
+			}
+			_ = conn.exec(ctx, "ANALYZE qail_bench_payload")
+		}
+		return nil
+	})
+}
+
+func ensureBenchManyParams(ctx context.Context, conn setupConn) error {
+	return withSetupLock(ctx, conn, func() error {
+		if err := conn.exec(ctx, buildManyParamsCreateSQL()); err != nil {
+			return err
+		}
+		if err := conn.exec(ctx, buildManyParamsIndexSQL()); err != nil {
+			return err
+		}
+
+		currentRows, err := conn.queryInt(ctx, "SELECT COALESCE(MAX(slot), 0) FROM qail_bench_many_params")
+		if err != nil {
+			return err
+		}
+		if currentRows < benchManyParamsTargetRows {
+			if err := conn.exec(ctx, buildManyParamsInsertSQL(currentRows+1, benchManyParamsTargetRows)); err != nil {
+				return err
+			}
+			_ = conn.exec(ctx, "ANALYZE qail_bench_many_params")
+		}
+		return nil
+	})
+}
+
+// ensureWorkloadTables creates and fills the tables spec reads.
+func ensureWorkloadTables(ctx context.Context, conn setupConn, spec modeWorkload) error {
+	if spec.requiresBenchPayload {
+		if err := ensureBenchPayload(ctx, conn); err != nil {
+			return err
+		}
+	}
+	if spec.requiresBenchManyParams {
+		if err := ensureBenchManyParams(ctx, conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ensureBenchIngest(ctx context.Context, conn setupConn) error {
+	return withSetupLock(ctx, conn, func() error {
+		return conn.exec(ctx, createBenchIngestSQL)
+	})
+}
+
+func buildIngestRows(total int) [][][]byte {
+	rows := make([][][]byte, 0, total)
+	for id := 1; id <= total; id++ {
+		var note []byte
+		if id%5 != 0 {
+			note = []byte(fmt.Sprintf("note-%08d-%s", id, strings.Repeat("x", id%32)))
+		}
+		rows = append(rows, [][]byte{
+			[]byte(strconv.Itoa(id)),
+			[]byte(fmt.Sprintf("ingest-%d", id)),
+			[]byte(strconv.Itoa(id * 11)),
+			[]byte(strconv.FormatBool(id%2 == 0)),
+			[]byte(strconv.FormatFloat(float64(id)/7.0, 'f', 3, 64)),
+			note,
+		})
+	}
+	return rows
+}
+
+// buildIngestChunkParams flattens rows into one parameter list per
+// multi-VALUES statement of rowsPerStmt rows. Both harnesses do this before
+// the timed window, so only the statements themselves are measured.
+func buildIngestChunkParams(rows [][][]byte, rowsPerStmt int) [][][]byte {
+	chunks := make([][][]byte, 0, (len(rows)+rowsPerStmt-1)/rowsPerStmt)
+	for start := 0; start < len(rows); start += rowsPerStmt {
+		end := min(start+rowsPerStmt, len(rows))
+		params := make([][]byte, 0, (end-start)*ingestColumnCount)
+		for _, row := range rows[start:end] {
+			params = append(params, row...)
+		}
+		chunks = append(chunks, params)
+	}
+	return chunks
+}
+
+func ingestPayloadBytes(rows [][][]byte) int {
+	total := 0
+	for _, row := range rows {
+		for _, value := range row {
+			total += len(value)
+		}
+	}
+	return total
+}
+
+func buildIngestMultiValuesSQL(rowCount int) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO qail_bench_ingest (")
+	b.WriteString(ingestColumns)
+	b.WriteString(") VALUES ")
+	for row := 0; row < rowCount; row++ {
+		if row > 0 {
+			b.WriteString(", ")
+		}
+		base := row * ingestColumnCount
+		b.WriteString(fmt.Sprintf(
+			"($%d::int, $%d::text, $%d::int, $%d::bool, $%d::numeric, $%d::text)",
+			base+1, base+2, base+3, base+4, base+5, base+6,
+		))
+	}
+	return b.String()
+}
+
+func consumeValues(mode resultMode, values [][]byte, stats *batchStats) {
+	switch mode {
+	case resultModePointRows:
+		stats.rows++
+		rowHash := fnvOffset
+		for idx, value := range values {
+			if value == nil {
+				rowHash = mixHash(rowHash, []byte("NULL"))
+				rowHash += uint64(idx)
+				continue
+			}
+
+			stats.bytes += len(value)
+			switch idx {
+			case 0:
+				parsed, err := strconv.ParseInt(string(value), 10, 64)
+				if err != nil {
+					parsed = int64(len(value))
+				}
+				rowHash += uint64(parsed)
+			default:
+				rowHash = mixHash(rowHash, value)
+			}
+		}
+		stats.checksum += rowHash
+	case resultModeScalarInt:
+		stats.rows++
+		if len(values) == 0 || values[0] == nil {
+			stats.checksum++
+			return
+		}
+
+		value := values[0]
+		stats.bytes += len(value)
+		parsed, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			parsed = int64(len(value))
+		}
+		stats.checksum += uint64(parsed)
+	case resultModeWideRows:
+		rowHash := fnvOffset
+		for idx, value := range values {
+			if value == nil {
+				rowHash = mixHash(rowHash, []byte("NULL"))
+				rowHash += uint64(idx)
+				continue
+			}
+
+			stats.bytes += len(value)
+			switch idx {
+			case 0, 4:
+				parsed, err := strconv.ParseInt(string(value), 10, 64)
+				if err != nil {
+					parsed = int64(len(value))
+				}
+				rowHash += uint64(parsed)
+			case 5:
+				if len(value) > 0 && (value[0] == 't' || value[0] == 'T') {
+					rowHash++
+				}
+			case 6:
+				parsed, err := strconv.ParseFloat(string(value), 64)
+				if err == nil {
+					rowHash += uint64(parsed * 1000.0)
+				}
+			default:
+				rowHash = mixHash(rowHash, value)
+			}
+		}
+		stats.rows++
+		stats.checksum += rowHash
+	case resultModeAggregateScalars:
+		stats.rows++
+		rowHash := fnvOffset
+		for _, value := range values {
+			if value == nil {
+				continue
+			}
+
+			stats.bytes += len(value)
+			parsed, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil {
+				parsed = int64(len(value))
+			}
+			rowHash += uint64(parsed)
+		}
+		stats.checksum += rowHash
+	}
+}
+
+func mixHash(seed uint64, bytes []byte) uint64 {
+	hash := seed
+	for _, b := range bytes {
+		hash ^= uint64(b)
+		hash *= fnvPrime
+	}
+	return hash
+}
+
+// latencyFromSamples summarises per-query latencies; samples is sorted in
+// place.
+func latencyFromSamples(samples []time.Duration, total time.Duration) LatencyResult {
+	slices.Sort(samples)
+	p50 := samples[len(samples)/2]
+	p95Idx := int(float64(len(samples))*0.95 + 0.999999999)
+	if p95Idx < 1 {
+		p95Idx = 1
+	}
+	if p95Idx > len(samples) {
+		p95Idx = len(samples)
+	}
+	p99Idx := int(float64(len(samples))*0.99 + 0.999999999)
+	if p99Idx < 1 {
+		p99Idx = 1
+	}
+	if p99Idx > len(samples) {
+		p99Idx = len(samples)
+	}
+
+	return LatencyResult{
+		AvgMs: total.Seconds() * 1000.0 / float64(len(samples)),
+		P50Ms: p50.Seconds() * 1000.0,
+		P95Ms: samples[p95Idx-1].Seconds() * 1000.0,
+		P99Ms: samples[p99Idx-1].Seconds() * 1000.0,
+	}
+}